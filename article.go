@@ -2,8 +2,6 @@ package main
 
 import (
 	"fmt"
-	"github.com/mitchellh/mapstructure"
-	"github.com/ntheanh201/blog/entity"
 	"html/template"
 	"path/filepath"
 	"strconv"
@@ -88,8 +86,44 @@ type Article struct {
 
 	UpdatedAgeStr string
 	Images        []*ImageMapping
+	// uploaded audio and video files
+	Media []*ImageMapping
+	// posters of YouTube and Vimeo videos, link is url of the video
+	videoPosters []*ImageMapping
 
 	blockInfos map[*notionapi.Block]*BlockInfo
+
+	// set when slug conflicts with slug of another article
+	urlSlug string
+	// set for unpublished articles that can be previewed
+	previewToken string
+
+	// name of a multi-part series this article is part of
+	Series string
+	// position in the series, 0 if not given
+	SeriesOrder int
+	series      *Series
+
+	// id of collection from config
+	collectionID string
+
+	// recommended articles, most similar first
+	RelatedArticles []*Article
+
+	// number of words in the body, not counting code blocks. For
+	// Chinese and Japanese we count characters
+	WordCount int
+	// number of words in code blocks
+	CodeWordCount int
+	// estimated reading time in minutes
+	ReadingTime int
+
+	// table of contents, from h1/h2/h3
+	TOC []*TOCEntry
+	// set with "toc" metadata, nil if not given
+	tocOverride *bool
+	// maps no-dash id of header block to its readable anchor
+	headerAnchors map[string]string
 }
 
 // URL returns article's permalink
@@ -97,7 +131,36 @@ func (a *Article) URL() string {
 	if a.urlOverride != "" {
 		return a.urlOverride
 	}
-	// TODO-ntheanh201: this is where handle article's url
+	return "/articles/" + a.URLSlug() + ".html"
+}
+
+// URLSlug returns the last part of article's url: Slug property
+// if given, transliterated title otherwise
+func (a *Article) URLSlug() string {
+	if a.urlSlug != "" {
+		return a.urlSlug
+	}
+	if s := slugify(a.Slug); s != "" {
+		return s
+	}
+	if s := slugify(a.Title); s != "" {
+		return s
+	}
+	return a.ID
+}
+
+// PreviewURL returns url under which unpublished article can be previewed
+func (a *Article) PreviewURL() string {
+	return "/preview/" + a.previewToken + "/" + a.URLSlug() + ".html"
+}
+
+// IsPreview returns true if this is a preview of unpublished article
+func (a *Article) IsPreview() bool {
+	return a.previewToken != ""
+}
+
+// titleURL is article's url from before we honored Slug property
+func (a *Article) titleURL() string {
 	return "/articles/" + urlify(a.Title) + ".html"
 }
 
@@ -138,8 +201,17 @@ func (a *Article) UpdatedAge() int {
 	return int(dur / (time.Hour * 24))
 }
 
+// IsScheduled returns true if article is published but its PublishedOn
+// is in the future
+func (a *Article) IsScheduled() bool {
+	return a.Status == statusPublished && a.PublishedOn.After(time.Now())
+}
+
 // IsHidden returns true if article should not be shown in the index
 func (a *Article) IsHidden() bool {
+	if a.IsScheduled() && !flgPreviewFuture {
+		return true
+	}
 	return a.Status == statusIdea || a.Status == statusDraft || a.Status == statusRevise
 }
 
@@ -208,7 +280,7 @@ func parseTags(s string) []string {
 		tag = strings.TrimSpace(tag)
 		tag = strings.ToLower(tag)
 		// skip the tag I use in quicknotes.io to tag notes for the blog
-		if tag == "for-blog" || tag == "published" || tag == "draft" {
+		if tag == "" || tag == "for-blog" || tag == "published" || tag == "draft" {
 			continue
 		}
 		res = append(res, tag)
@@ -275,19 +347,14 @@ func (a *Article) setStatusMust(val string) {
 }
 
 func (a *Article) setCollectionMust(val string) {
-	collectionURL := ""
-	switch val {
-	case "go-cookbook":
-		collectionURL = "/book/go-cookbook.html"
-		val = "Go Cookbook"
-	case "go-windows":
-		// ignore
+	c := findCollectionConfig(val)
+	panicIf(c == nil, "'%s' is not a known collection. Collections in config: %s", val, strings.Join(collectionConfigIDs(), ", "))
+	if c.Ignore {
 		return
 	}
-	panicIf(collectionURL == "", "'%s' is not a known collection", val)
-	a.Collection = val
-	a.CollectionURL = collectionURL
-
+	a.collectionID = c.ID
+	a.Collection = c.Name
+	a.CollectionURL = c.URL
 }
 
 func (a *Article) setHeaderImageMust(val string) {
@@ -461,8 +528,19 @@ func (a *Article) maybeParseMeta(nBlock int, block *notionapi.Block) bool {
 		a.setHeaderImageMust(val)
 	case "collection":
 		a.setCollectionMust(val)
+	case "series":
+		if !isSeriesName(val) {
+			return false
+		}
+		a.Series = val
+	case "seriesorder":
+		a.SeriesOrder = parseSeriesOrder(val)
 	case "url":
 		a.urlOverride = val
+	case "toc":
+		if !a.setTOCOverride(val) {
+			return false
+		}
 	default:
 		// assume that unrecognized meta means this article doesn't have
 		// proper meta tags. It might miss meta-tags that are badly named
@@ -520,6 +598,15 @@ func (a *Article) processBlocks(blocks []*notionapi.Block) {
 			continue
 		}
 
+		if (block.Type == notionapi.BlockAudio || block.Type == notionapi.BlockVideo) && len(block.FileIDs) > 0 {
+			a.downloadMedia(block)
+			continue
+		}
+		if block.Type == notionapi.BlockVideo {
+			a.downloadVideoPoster(block)
+			continue
+		}
+
 		if len(block.Content) > 0 {
 			a.processBlocks(block.Content)
 		}
@@ -548,36 +635,8 @@ func notionPageToArticle(c *notionapi.CachingClient, page *notionapi.Page) *Arti
 	title := root.Title
 	id := normalizeID(root.ID)
 
-	properties := page.Root().Properties
-	var blockExtend map[string][][]string
-	err := mapstructure.Decode(properties, &blockExtend)
-	if err != nil {
-		fmt.Println("error unmarshal block extend with properties")
-	}
-
-	//itemsMap := blockExtend.(map[string]interface{})
-	itemsMap := blockExtend
-	var item entity.BlockExtend
-	// Loop through the Items; we're not interested in the key, just the values
-	for key, v := range itemsMap {
-		// Use type assertions to ensure that the value's a JSON object
-		//fmt.Println("key: ", key, v)
-		switch key {
-		//case "NX\\Q":
-		//	item.StartDate = v[0][0]
-		case "`gQ~":
-			item.Type = v[0][0]
-		case "title":
-			item.Title = v[0][0]
-		case "d]hq":
-			item.Slug = v[0][0]
-		case "sD^m":
-			item.Tags = v[0]
-		case "f211bdc0-ee00-4186-9a7d-f68c055ec2ee":
-			item.Status = v[0][0]
-		}
-		//fmt.Println("item: ", item)
-	}
+	item, err := notionPageProperties(c, page)
+	must(err)
 
 	a := &Article{
 		page:         page,
@@ -586,6 +645,13 @@ func notionPageToArticle(c *notionapi.CachingClient, page *notionapi.Page) *Arti
 		notionClient: c,
 		Tags:         item.Tags,
 		Type:         item.Type,
+		Slug:         item.Slug,
+		Description:  item.Description,
+		Series:       item.Series,
+		SeriesOrder:  parseSeriesOrder(item.SeriesOrder),
+	}
+	if item.Title != "" {
+		a.Title = item.Title
 	}
 
 	switch item.Status {
@@ -610,6 +676,11 @@ func notionPageToArticle(c *notionapi.CachingClient, page *notionapi.Page) *Arti
 
 	a.PublishedOn = root.CreatedOn()
 	a.UpdatedOn = root.LastEditedOn()
+	if item.PublishedOn != "" {
+		a.PublishedOn, err = parseDate(item.PublishedOn)
+		must(err)
+		a.inBlog = true
+	}
 
 	a.processBlocks(page.Root().Content)
 
@@ -629,11 +700,14 @@ func notionPageToArticle(c *notionapi.CachingClient, page *notionapi.Page) *Arti
 		a.Paths = append(a.Paths, path)
 	}
 
-	format := root.FormatPage()
-	// set image header from cover page
-	if a.HeaderImageURL == "" && format != nil && format.PageCover != "" {
-		rsp, err := c.DownloadFile(format.PageCover, root)
-		panicIf(err != nil, "downloading '%s' for page '%s' failed with '%s'", format.PageCover, root.ID, err)
+	// set image header from Cover property or cover page
+	coverURL := item.Cover
+	if format := root.FormatPage(); coverURL == "" && format != nil {
+		coverURL = format.PageCover
+	}
+	if a.HeaderImageURL == "" && coverURL != "" {
+		rsp, err := c.DownloadFile(coverURL, root)
+		panicIf(err != nil, "downloading '%s' for page '%s' failed with '%s'", coverURL, root.ID, err)
 		path := rsp.CacheFilePath
 		relURL := "/img/" + filepath.Base(path)
 		im := &ImageMapping{
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestScheduledArticles(t *testing.T) {
+	now := time.Now()
+	past := &Article{ID: "a", Title: "Past", Slug: "past", PublishedOn: now.Add(-time.Hour)}
+	future := &Article{ID: "b", Title: "Future", Slug: "future", PublishedOn: now.Add(48 * time.Hour)}
+	draft := &Article{ID: "c", Title: "Draft", Status: statusDraft, PublishedOn: now.Add(24 * time.Hour)}
+
+	assert.False(t, past.IsScheduled())
+	assert.False(t, past.IsHidden())
+	assert.True(t, future.IsScheduled())
+	assert.True(t, future.IsHidden())
+	assert.False(t, draft.IsScheduled())
+	assert.True(t, draft.IsHidden())
+
+	flgPreviewFuture = true
+	assert.False(t, future.IsHidden())
+	assert.True(t, draft.IsHidden())
+	flgPreviewFuture = false
+
+	store := &Articles{
+		idToArticle: map[string]*Article{"a": past, "b": future, "b-legacy": future, "c": draft},
+	}
+	scheduled := store.getScheduled()
+	assert.Equal(t, 1, len(scheduled))
+	assert.Equal(t, future, scheduled[0])
+
+	var buf bytes.Buffer
+	listScheduledArticles(store, &buf)
+	assert.True(t, bytes.Contains(buf.Bytes(), []byte("/articles/future.html  Future")))
+}
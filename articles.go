@@ -1,17 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"github.com/kjk/notionapi"
 	"html/template"
+	"io"
+	"net/http"
 	"sort"
 	"time"
 )
 
-var (
-	notionBlogsStartPage   = "cbbc16640fc24a7a9fb24660356a4409"
-	notionWebsiteStartPage = "68f077a6dfb346358f219875e80ea72c"
-)
-
 // Articles has info about all articles downloaded from notion
 type Articles struct {
 	idToArticle map[string]*Article
@@ -24,6 +22,12 @@ type Articles struct {
 	blog []*Article
 	// blog articles that are not hidden
 	blogNotHidden []*Article
+	// hidden articles that have a preview url, only with -preview-drafts
+	previews []*Article
+	// multi-part series of published articles
+	series []*Series
+	// collections defined in config, with their published articles
+	collections []*Collection
 }
 
 func (a *Articles) getNotHidden() []*Article {
@@ -119,13 +123,14 @@ func buildArticlesNavigation(articles *Articles) {
 	isRoot := func(id string) bool {
 		id = notionapi.ToNoDashID(id)
 		switch id {
-		case notionBlogsStartPage, notionWebsiteStartPage:
+		case siteConfig.Notion.BlogsStartPage, siteConfig.Notion.WebsiteStartPage:
 			return true
 		}
 		return false
 	}
 
-	for _, article := range articles.articles {
+	toBuild := append(append([]*Article{}, articles.articles...), articles.previews...)
+	for _, article := range toBuild {
 		buildArticleNavigation(article, isRoot, idToBlock, articles.idToArticle)
 	}
 }
@@ -137,7 +142,6 @@ func loadArticles(d *notionapi.CachingClient) *Articles {
 		logf(ctx(), "d.PreLoadCache() finished in %s\n", time.Since(timeStart))
 	}
 
-	res := &Articles{}
 	nFromCache := 0
 	nReq := 0
 	timeStart := time.Now()
@@ -164,7 +168,7 @@ func loadArticles(d *notionapi.CachingClient) *Articles {
 	_, err := downloadPagesRecursively(d, page, afterDl)
 	must(err)
 	//res.idToPage = pages
-	res.idToPage = map[string]*notionapi.Page{}
+	idToPage := map[string]*notionapi.Page{}
 	for id, cp := range d.IdToCachedPage {
 		page := cp.PageFromServer
 
@@ -174,10 +178,18 @@ func loadArticles(d *notionapi.CachingClient) *Articles {
 		if page == nil {
 			continue
 		}
-		res.idToPage[id] = page
+		idToPage[id] = page
 	}
+	return buildArticles(d, idToPage)
+}
 
-	res.idToArticle = map[string]*Article{}
+// buildArticles converts already downloaded pages to articles
+func buildArticles(d *notionapi.CachingClient, idToPage map[string]*notionapi.Page) *Articles {
+	res := &Articles{
+		idToPage:    idToPage,
+		idToArticle: map[string]*Article{},
+	}
+	var hidden []*Article
 	for id, page := range res.idToPage {
 		panicIf(id != notionapi.ToNoDashID(id), "bad id '%s' sneaked in", id)
 		article := notionPageToArticle(d, page)
@@ -195,16 +207,34 @@ func loadArticles(d *notionapi.CachingClient) *Articles {
 		// filter only Published post to be posted
 		if !article.IsHidden() {
 			res.articles = append(res.articles, article)
+		} else {
+			hidden = append(hidden, article)
 		}
 	}
 
-	for _, article := range res.articles {
+	assignArticleURLs(res.articles)
+	trackURLHistory(res.articles)
+	res.series = buildSeries(res.articles)
+	res.collections = buildCollections(res.articles)
+	if flgPreviewDrafts {
+		res.previews = buildPreviews(hidden)
+	}
+
+	toHTML := append(append([]*Article{}, res.articles...), res.previews...)
+	// anchors must be known before generating html because
+	// articles link to headers of other articles
+	for _, article := range toHTML {
+		article.TOC, article.headerAnchors = buildTOC(article.page)
+	}
+	for _, article := range toHTML {
 		html, images := notionToHTML(d, article, res)
 		article.BodyHTML = string(html)
 		article.HTMLBody = template.HTML(article.BodyHTML)
 		article.Images = append(article.Images, images...)
+		setReadingStats(article)
 	}
 
+	buildRelatedArticles(res.articles)
 	buildArticlesNavigation(res)
 
 	sort.Slice(res.blog, func(i, j int) bool {
@@ -214,6 +244,72 @@ func loadArticles(d *notionapi.CachingClient) *Articles {
 	return res
 }
 
+// assignArticleURLs makes sure that articles have unique urls and
+// redirects old, title-based urls to current urls
+func assignArticleURLs(articles []*Article) {
+	// older articles get to keep their url
+	sorted := append([]*Article{}, articles...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a1 := sorted[i]
+		a2 := sorted[j]
+		if !a1.PublishedOn.Equal(a2.PublishedOn) {
+			return a1.PublishedOn.Before(a2.PublishedOn)
+		}
+		return a1.ID < a2.ID
+	})
+
+	urlToArticle := map[string]*Article{}
+	for _, a := range sorted {
+		uri := a.URL()
+		if other := urlToArticle[uri]; other != nil {
+			panicIf(a.urlOverride != "", "url '%s' of article %s conflicts with article %s", uri, a.ID, other.ID)
+			a.urlSlug = a.URLSlug() + "-" + a.ID
+			logf(ctx(), "assignArticleURLs: url '%s' of article %s conflicts with article %s, changed to '%s'\n", uri, a.ID, other.ID, a.URL())
+			uri = a.URL()
+		}
+		urlToArticle[uri] = a
+	}
+
+	for _, a := range sorted {
+		from := a.titleURL()
+		to := a.URL()
+		if from == to || urlToArticle[from] != nil {
+			continue
+		}
+		logvf("redirect: %s => %s\n", from, to)
+		addRedirect(from, to, http.StatusMovedPermanently)
+	}
+}
+
+// getScheduled returns articles scheduled to be published in the future,
+// soonest first
+func (a *Articles) getScheduled() []*Article {
+	var res []*Article
+	seen := map[*Article]bool{}
+	for _, article := range a.idToArticle {
+		if seen[article] || !article.IsScheduled() {
+			continue
+		}
+		seen[article] = true
+		res = append(res, article)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].PublishedOn.Before(res[j].PublishedOn)
+	})
+	return res
+}
+
+func listScheduledArticles(store *Articles, w io.Writer) {
+	articles := store.getScheduled()
+	if len(articles) == 0 {
+		fmt.Fprintf(w, "no scheduled articles\n")
+		return
+	}
+	for _, a := range articles {
+		fmt.Fprintf(w, "%s  %s  %s\n", a.PublishedOn.Format("2006-01-02 15:04"), a.URL(), a.Title)
+	}
+}
+
 // MonthArticle combines article and a month
 type MonthArticle struct {
 	*Article
@@ -295,7 +391,7 @@ func downloadPagesRecursively(c *notionapi.CachingClient, toVisit []string, afte
 	for len(toVisit) > 0 {
 		pageID := notionapi.ToDashID(toVisit[0])
 
-		if pageID == notionapi.ToDashID(notionWebsiteStartPage) {
+		if pageID == notionapi.ToDashID(siteConfig.Notion.WebsiteStartPage) {
 			toVisit = toVisit[1:]
 			continue
 		}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Atom feeds (RFC 4287). We generate them ourselves because we need
+// control over entry ids and <updated>
+
+// first year of the blog, part of tag: URIs of entries. Must never change
+const atomTagYear = "2022"
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID         string          `xml:"id"`
+	Title      string          `xml:"title"`
+	Links      []*atomLink     `xml:"link"`
+	Published  string          `xml:"published"`
+	Updated    string          `xml:"updated"`
+	Authors    []*atomPerson   `xml:"author"`
+	Categories []*atomCategory `xml:"category"`
+	Summary    *atomText       `xml:"summary"`
+	Content    *atomText       `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name      `xml:"feed"`
+	Ns      string        `xml:"xmlns,attr"`
+	ID      string        `xml:"id"`
+	Title   string        `xml:"title"`
+	Updated string        `xml:"updated"`
+	Links   []*atomLink   `xml:"link"`
+	Authors []*atomPerson `xml:"author"`
+	Entries []*atomEntry  `xml:"entry"`
+}
+
+// atomEntryID returns a permanent id of an article, derived from its
+// Notion page id, so that it doesn't change when title, url or host
+// change e.g. "tag:ntheanh201.vercel.app,2022:0367c2db381a4f8b9ce360f388a6b2e3"
+func atomEntryID(a *Article) string {
+	id := a.ID
+	if a.page != nil {
+		id = normalizeID(a.page.ID)
+	}
+	return "tag:" + siteConfig.AtomIDAuthority + "," + atomTagYear + ":" + id
+}
+
+// articleUpdatedOn returns when article was last updated, but never
+// earlier than it was published
+func articleUpdatedOn(a *Article) time.Time {
+	if a.UpdatedOn.After(a.PublishedOn) {
+		return a.UpdatedOn
+	}
+	return a.PublishedOn
+}
+
+func atomAuthors() []*atomPerson {
+	author := siteConfig.Author
+	if author.Name == "" {
+		return nil
+	}
+	p := &atomPerson{
+		Name: author.Name,
+		URI:  author.URL,
+	}
+	return []*atomPerson{p}
+}
+
+// genAtomXMLForArticles generates atom feed at feedURL from articles
+// sorted newest first
+func genAtomXMLForArticles(latest []*Article, title string, feedURL string) ([]byte, error) {
+	authors := atomAuthors()
+	// atom requires an author on the feed or on every entry
+	if authors == nil {
+		authors = []*atomPerson{{Name: siteConfig.Title}}
+	}
+	feed := &atomFeed{
+		Ns:    "http://www.w3.org/2005/Atom",
+		ID:    getHostURL() + feedURL,
+		Title: title,
+		Links: []*atomLink{
+			{Href: getHostURL() + feedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: getHostURL() + "/", Rel: "alternate", Type: "text/html"},
+		},
+		Authors: authors,
+	}
+
+	var updated time.Time
+	for _, a := range latest {
+		entryUpdated := articleUpdatedOn(a)
+		if entryUpdated.After(updated) {
+			updated = entryUpdated
+		}
+		e := &atomEntry{
+			ID:    atomEntryID(a),
+			Title: a.Title,
+			Links: []*atomLink{
+				{Href: getHostURL() + a.URL(), Rel: "alternate", Type: "text/html"},
+			},
+			Published: a.PublishedOn.Format(time.RFC3339),
+			Updated:   entryUpdated.Format(time.RFC3339),
+			Authors:   authors,
+			Content:   &atomText{Type: "html", Value: a.BodyHTML},
+		}
+		if summary := articleSummary(a, htmlToText(a.BodyHTML)); summary != "" {
+			e.Summary = &atomText{Type: "text", Value: summary}
+		}
+		for _, tag := range a.Tags {
+			e.Categories = append(e.Categories, &atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+
+	d, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), d...), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+// validateAtomFeed checks requirements of RFC 4287 that matter to
+// feed readers
+func validateAtomFeed(d []byte) error {
+	var feed atomFeed
+	if err := xml.Unmarshal(d, &feed); err != nil {
+		return err
+	}
+	if feed.XMLName.Space != "http://www.w3.org/2005/Atom" {
+		return fmt.Errorf("bad namespace '%s'", feed.XMLName.Space)
+	}
+	if feed.ID == "" || feed.Title == "" {
+		return fmt.Errorf("feed must have id and title")
+	}
+	if _, err := time.Parse(time.RFC3339, feed.Updated); err != nil {
+		return fmt.Errorf("feed has invalid updated: %w", err)
+	}
+	hasSelf := false
+	for _, l := range feed.Links {
+		hasSelf = hasSelf || l.Rel == "self"
+	}
+	if !hasSelf {
+		return fmt.Errorf("feed must have rel=self link")
+	}
+	ids := map[string]bool{}
+	for i, e := range feed.Entries {
+		if !strings.HasPrefix(e.ID, "tag:") {
+			return fmt.Errorf("entry %d has id '%s' that is not tag: URI", i, e.ID)
+		}
+		if ids[e.ID] {
+			return fmt.Errorf("duplicate entry id '%s'", e.ID)
+		}
+		ids[e.ID] = true
+		if e.Title == "" {
+			return fmt.Errorf("entry %d has no title", i)
+		}
+		published, err := time.Parse(time.RFC3339, e.Published)
+		if err != nil {
+			return fmt.Errorf("entry %d has invalid published: %w", i, err)
+		}
+		updated, err := time.Parse(time.RFC3339, e.Updated)
+		if err != nil {
+			return fmt.Errorf("entry %d has invalid updated: %w", i, err)
+		}
+		if updated.Before(published) {
+			return fmt.Errorf("entry %d was updated before it was published", i)
+		}
+		if len(feed.Authors) == 0 && len(e.Authors) == 0 {
+			return fmt.Errorf("entry %d has no author", i)
+		}
+		for _, c := range e.Categories {
+			if c.Term == "" {
+				return fmt.Errorf("entry %d has category without term", i)
+			}
+		}
+	}
+	return nil
+}
+
+func TestGenAtomXML(t *testing.T) {
+	store := makeFeedTestStore()
+	d, err := genAtomXML(store, false)
+	assert.NoError(t, err)
+	assert.NoError(t, validateAtomFeed(d))
+
+	var feed atomFeed
+	assert.NoError(t, xml.Unmarshal(d, &feed))
+	assert.Equal(t, 2, len(feed.Entries))
+	e := feed.Entries[1]
+	assert.Equal(t, "tag:ntheanh201.vercel.app,2022:a1", e.ID)
+	assert.Equal(t, "2022-03-04T05:06:07Z", e.Published)
+	assert.Equal(t, "2022-03-04T06:06:07Z", e.Updated)
+	assert.Equal(t, "go", e.Categories[0].Term)
+	assert.Equal(t, "Short summary", e.Summary.Value)
+	assert.Equal(t, "<p>Hello <b>world</b></p>", e.Content.Value)
+	// feed is as fresh as the newest entry
+	assert.Equal(t, "2022-03-05T05:06:07Z", feed.Updated)
+
+	// id doesn't depend on title or url
+	a := store.articles[0]
+	a.Title = "New title"
+	a.Slug = "new-slug"
+	assert.Equal(t, e.ID, atomEntryID(a))
+
+	// nor on the host e.g. -host for staging builds
+	prevHost := siteConfig.HostURL
+	siteConfig.HostURL = "https://staging.example.com"
+	defer func() { siteConfig.HostURL = prevHost }()
+	assert.Equal(t, e.ID, atomEntryID(a))
+}
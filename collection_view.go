@@ -7,7 +7,7 @@ import (
 )
 
 func CollectionViewToPages(d *notionapi.CachingClient) []string {
-	s := []string{"68f077a6dfb346358f219875e80ea72c"}
+	s := []string{siteConfig.Notion.WebsiteStartPage}
 	out, _ := d.Client.GetBlockRecords(s)
 	var pages []string
 	for _, b := range out {
@@ -29,6 +29,7 @@ func CollectionViewToPages(d *notionapi.CachingClient) []string {
 			}
 			query := notionapi.Query{}
 			coll, _ := d.Client.QueryCollection(req, &query)
+			rememberCollectionSchemas(coll.RecordMap)
 
 			for _, g := range coll.RecordMap.Blocks {
 				var bb notionapi.Record
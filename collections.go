@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CollectionConfig describes a collection of articles, like a book.
+// Articles join a collection with "collection: ${id}" metadata line
+type CollectionConfig struct {
+	// used in "collection:" metadata line e.g. "go-cookbook"
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// url of the landing page. Default is /collection/${id}.html
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	// how articles are ordered on the landing page:
+	// "publishedOn" (oldest first, the default), "-publishedOn" (newest
+	// first), "title" or "order" (SeriesOrder)
+	OrderBy string `json:"orderBy"`
+	// if true, "collection:" with this id is accepted but ignored
+	Ignore bool `json:"ignore,omitempty"`
+}
+
+// Collection is a collection and its published articles
+type Collection struct {
+	*CollectionConfig
+	Articles []*Article
+}
+
+func findCollectionConfig(id string) *CollectionConfig {
+	for _, c := range siteConfig.Collections {
+		if strings.EqualFold(c.ID, id) {
+			return c
+		}
+	}
+	return nil
+}
+
+func collectionConfigIDs() []string {
+	var res []string
+	for _, c := range siteConfig.Collections {
+		res = append(res, c.ID)
+	}
+	return res
+}
+
+// validateCollections fills defaults and checks that collections in
+// config are well formed
+func validateCollections(collections []*CollectionConfig) error {
+	seenID := map[string]bool{}
+	seenURL := map[string]bool{}
+	for _, c := range collections {
+		if c.ID == "" {
+			return fmt.Errorf("collection '%s' is missing id", c.Name)
+		}
+		id := strings.ToLower(c.ID)
+		if seenID[id] {
+			return fmt.Errorf("duplicate collection id '%s'", c.ID)
+		}
+		seenID[id] = true
+		if c.Ignore {
+			continue
+		}
+		if c.Name == "" {
+			c.Name = c.ID
+		}
+		if c.URL == "" {
+			c.URL = "/collection/" + slugify(c.ID) + ".html"
+		}
+		if !strings.HasPrefix(c.URL, "/") {
+			c.URL = "/" + c.URL
+		}
+		if seenURL[c.URL] {
+			return fmt.Errorf("collection '%s' has duplicate url '%s'", c.ID, c.URL)
+		}
+		seenURL[c.URL] = true
+		switch c.OrderBy {
+		case "":
+			c.OrderBy = "publishedOn"
+		case "publishedOn", "-publishedOn", "title", "order":
+			// valid
+		default:
+			return fmt.Errorf("collection '%s' has invalid orderBy '%s'", c.ID, c.OrderBy)
+		}
+	}
+	return nil
+}
+
+func sortCollectionArticles(articles []*Article, orderBy string) {
+	byPublishedOn := func(a1, a2 *Article) bool {
+		if !a1.PublishedOn.Equal(a2.PublishedOn) {
+			return a1.PublishedOn.Before(a2.PublishedOn)
+		}
+		return a1.ID < a2.ID
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		a1 := articles[i]
+		a2 := articles[j]
+		switch orderBy {
+		case "-publishedOn":
+			return byPublishedOn(a2, a1)
+		case "title":
+			if a1.Title != a2.Title {
+				return strings.ToLower(a1.Title) < strings.ToLower(a2.Title)
+			}
+		case "order":
+			if a1.SeriesOrder != a2.SeriesOrder {
+				return a1.SeriesOrder < a2.SeriesOrder
+			}
+		}
+		return byPublishedOn(a1, a2)
+	})
+}
+
+// buildCollections returns non-empty collections from config with their
+// articles, in the order of config
+func buildCollections(articles []*Article) []*Collection {
+	var res []*Collection
+	for _, cfg := range siteConfig.Collections {
+		if cfg.Ignore {
+			continue
+		}
+		c := &Collection{
+			CollectionConfig: cfg,
+		}
+		for _, a := range articles {
+			if a.collectionID == cfg.ID {
+				c.Articles = append(c.Articles, a)
+			}
+		}
+		if len(c.Articles) == 0 {
+			continue
+		}
+		sortCollectionArticles(c.Articles, cfg.OrderBy)
+		res = append(res, c)
+	}
+	return res
+}
+
+// landing page of a collection
+func genCollection(c *Collection, w io.Writer) error {
+	model := struct {
+		Article    *Article
+		Collection *Collection
+		FeedURL    string
+	}{
+		Collection: c,
+		FeedURL:    topicFeedURL(c.URL),
+	}
+	return execTemplate(c.URL, "collection.tmpl.html", model, w)
+}
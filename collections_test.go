@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestCollections(t *testing.T) {
+	collections := []*CollectionConfig{
+		{ID: "go-cookbook", Name: "Go Cookbook", URL: "/book/go-cookbook.html"},
+		{ID: "notes", OrderBy: "-publishedOn"},
+		{ID: "go-windows", Ignore: true},
+	}
+	assert.NoError(t, validateCollections(collections))
+	assert.Equal(t, "publishedOn", collections[0].OrderBy)
+	assert.Equal(t, "/collection/notes.html", collections[1].URL)
+	assert.Equal(t, "notes", collections[1].Name)
+
+	assert.Error(t, validateCollections([]*CollectionConfig{{ID: "a"}, {ID: "A"}}))
+	assert.Error(t, validateCollections([]*CollectionConfig{{ID: "a", OrderBy: "random"}}))
+
+	prev := siteConfig
+	defer func() {
+		siteConfig = prev
+	}()
+	cfg := *siteConfig
+	cfg.Collections = collections
+	siteConfig = &cfg
+
+	t1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	a1 := &Article{ID: "a1", PublishedOn: t1.Add(time.Hour)}
+	a2 := &Article{ID: "a2", PublishedOn: t1}
+	a3 := &Article{ID: "a3"}
+	a1.setCollectionMust("notes")
+	a2.setCollectionMust("Notes")
+	a3.setCollectionMust("go-windows")
+	assert.Equal(t, "/collection/notes.html", a1.CollectionURL)
+	assert.Equal(t, "", a3.Collection)
+	func() {
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+		a3.setCollectionMust("unknown")
+	}()
+
+	res := buildCollections([]*Article{a2, a1, a3})
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, []*Article{a1, a2}, res[0].Articles)
+}
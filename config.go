@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// NotionProperties maps logical article fields to names of columns
+// in the Notion database. Empty name means the field is not read
+// from the database
+type NotionProperties struct {
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	Tags        string `json:"tags"`
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	PublishedOn string `json:"publishedOn"`
+	Description string `json:"description"`
+	Cover       string `json:"cover"`
+	Series      string `json:"series"`
+	SeriesOrder string `json:"seriesOrder"`
+}
+
+// NotionConfig describes where and how we read articles from Notion
+type NotionConfig struct {
+	// id of the page with the database of articles
+	WebsiteStartPage string `json:"websiteStartPage"`
+	// id of the page with blog posts
+	BlogsStartPage string           `json:"blogsStartPage"`
+	Properties     NotionProperties `json:"properties"`
+}
+
+// SiteConfig describes site-specific settings
+type SiteConfig struct {
+	// e.g. "https://ntheanh201.vercel.app", without trailing "/"
+	HostURL string `json:"hostURL"`
+	// title of atom feeds
+	Title string `json:"title"`
+	// description of the site, used in feeds
+	Description string `json:"description"`
+	// author of articles, used in feeds
+	Author AuthorConfig `json:"author"`
+	// twitter handle (without "@") used in "via=" of tweet share urls
+	TwitterHandle string `json:"twitterHandle"`
+	// name of top-level directory of the repository. We cd to it on startup
+	Dir string `json:"dir"`
+	// url under which gitoembed is available. If empty, we use HostURL
+	GitOembedBaseURL string `json:"gitoembedBaseURL"`
+	// domain in tag: ids of atom feed entries e.g. "ntheanh201.vercel.app".
+	// Unlike HostURL it must never change, even if the site moves,
+	// or feed readers show all entries as new
+	AtomIDAuthority string `json:"atomIDAuthority"`
+
+	Notion NotionConfig `json:"notion"`
+
+	Collections []*CollectionConfig `json:"collections"`
+
+	Pagination PaginationConfig `json:"pagination"`
+}
+
+// AuthorConfig describes author of the articles
+type AuthorConfig struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// PaginationConfig describes how many articles are shown per page.
+// 0 means everything on one page
+type PaginationConfig struct {
+	IndexPageSize   int `json:"indexPageSize"`
+	ArchivePageSize int `json:"archivePageSize"`
+}
+
+var (
+	configPath = "config.json"
+
+	siteConfig = &SiteConfig{
+		HostURL:       "https://ntheanh201.vercel.app",
+		Title:         "The Anh Nguyen blog",
+		TwitterHandle: "ntheanh201",
+		Description:   "The site of The Anh Nguyen, software/devops engineer",
+		Author: AuthorConfig{
+			Name: "The Anh Nguyen",
+			URL:  "https://ntheanh201.vercel.app",
+		},
+		Dir:             "blog",
+		AtomIDAuthority: "ntheanh201.vercel.app",
+		Notion: NotionConfig{
+			WebsiteStartPage: "68f077a6dfb346358f219875e80ea72c",
+			BlogsStartPage:   "cbbc16640fc24a7a9fb24660356a4409",
+			Properties: NotionProperties{
+				Title:  "title",
+				Slug:   "slug",
+				Tags:   "tags",
+				Type:   "type",
+				Status: "status",
+			},
+		},
+		Pagination: PaginationConfig{
+			IndexPageSize:   25,
+			ArchivePageSize: 100,
+		},
+		Collections: []*CollectionConfig{
+			{
+				ID:   "go-cookbook",
+				Name: "Go Cookbook",
+				URL:  "/book/go-cookbook.html",
+			},
+			{
+				ID:     "go-windows",
+				Ignore: true,
+			},
+		},
+	}
+)
+
+// loadSiteConfig over-writes default config with values from config file,
+// if it exists
+func loadSiteConfig(path string) (*SiteConfig, error) {
+	res := *siteConfig
+	// collections are pointers so we must not decode into defaults
+	res.Collections = nil
+	if fileExists(path) {
+		d := readFileMust(path)
+		if err := json.Unmarshal(d, &res); err != nil {
+			return nil, fmt.Errorf("loadSiteConfig: failed to parse '%s': %w", path, err)
+		}
+	} else {
+		logf(ctx(), "loadSiteConfig: '%s' doesn't exist, using defaults\n", path)
+	}
+	if res.Collections == nil {
+		for _, c := range siteConfig.Collections {
+			cc := *c
+			res.Collections = append(res.Collections, &cc)
+		}
+	}
+	res.HostURL = strings.TrimSuffix(res.HostURL, "/")
+	if res.AtomIDAuthority == "" {
+		return nil, fmt.Errorf("loadSiteConfig: '%s': atomIDAuthority must be set", path)
+	}
+	res.Notion.WebsiteStartPage = normalizeID(res.Notion.WebsiteStartPage)
+	res.Notion.BlogsStartPage = normalizeID(res.Notion.BlogsStartPage)
+	if err := validateCollections(res.Collections); err != nil {
+		return nil, fmt.Errorf("loadSiteConfig: '%s': %w", path, err)
+	}
+	return &res, nil
+}
+
+// findConfigFile looks for config file in current directory and its
+// parents. Returns path unchanged if not found
+func findConfigFile(path string) string {
+	if filepath.IsAbs(path) || fileExists(path) {
+		return path
+	}
+	dir := currDirAbsMust()
+	for {
+		try := filepath.Join(dir, path)
+		if fileExists(try) {
+			return try
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return path
+		}
+		dir = parentDir
+	}
+}
+
+// getHostURL returns e.g. "https://ntheanh201.vercel.app"
+func getHostURL() string {
+	return siteConfig.HostURL
+}
+
+func getGitOembedBaseURL() string {
+	if siteConfig.GitOembedBaseURL != "" {
+		return siteConfig.GitOembedBaseURL
+	}
+	return getHostURL()
+}
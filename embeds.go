@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kjk/notionapi"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Tweets, gists, Codepen, Figma and Google Maps embeds are rendered
+// without third-party scripts. Data for them is downloaded when
+// importing from Notion (oEmbed or GitHub API) and cached in
+// notion_cache/embeds so that -gen doesn't need network
+
+// over-written in tests
+var (
+	oembedTwitterURL = "https://publish.twitter.com/oembed"
+	oembedCodepenURL = "https://codepen.io/api/oembed"
+	oembedFigmaURL   = "https://www.figma.com/api/oembed"
+	githubAPIURL     = "https://api.github.com"
+)
+
+const embedIframeDefaultHeight = 400
+
+var (
+	rxIframeSrc = regexp.MustCompile(`<iframe[^>]*\ssrc="([^"]+)"`)
+	rxGistID    = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	rxMapsCoord = regexp.MustCompile(`@(-?[0-9.]+),(-?[0-9.]+)`)
+	// google.com, www.google.co.uk etc.
+	rxGoogleHost = regexp.MustCompile(`^(www\.)?google\.[a-z]{2,3}(\.[a-z]{2})?$`)
+
+	// tweet's oEmbed html without scripts
+	tweetPolicy = bluemonday.UGCPolicy()
+)
+
+func newEmbedsCache() *HTTPDiskCache {
+	dir := filepath.Join(cacheDir, "embeds")
+	return NewHTTPDiskCache(dir, cachingPolicy == notionapi.PolicyCacheOnly)
+}
+
+func fetchOEmbed(cache *HTTPDiskCache, endpoint string, uri string, params url.Values) (*oembedResult, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("url", uri)
+	params.Set("format", "json")
+	d, _, err := cache.Download(endpoint + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var res oembedResult
+	if err = json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// tweetEmbedHTML returns a static card with text of the tweet
+func tweetEmbedHTML(cache *HTTPDiskCache, uri string) (string, error) {
+	params := url.Values{}
+	params.Set("omit_script", "true")
+	params.Set("dnt", "true")
+	res, err := fetchOEmbed(cache, oembedTwitterURL, uri, params)
+	if err != nil {
+		return "", err
+	}
+	if res.HTML == "" {
+		return "", fmt.Errorf("no html in oEmbed response for '%s'", uri)
+	}
+	s := tweetPolicy.Sanitize(res.HTML)
+	return `<div class="tweet-card">` + s + `</div>`, nil
+}
+
+// embedHeight returns height from oEmbed response in pixels
+func embedHeight(v interface{}) int {
+	switch h := v.(type) {
+	case float64:
+		if h > 0 {
+			return int(h)
+		}
+	case string:
+		var n int
+		if _, err := fmt.Sscanf(h, "%d", &n); err == nil && n > 0 && !strings.HasSuffix(h, "%") {
+			return n
+		}
+	}
+	return embedIframeDefaultHeight
+}
+
+// iframeEmbedHTML returns lazy-loaded iframe with the embed url from
+// oEmbed response. srcPrefix is the only allowed origin of the iframe
+func iframeEmbedHTML(cache *HTTPDiskCache, endpoint string, uri string, srcPrefix string) (string, error) {
+	res, err := fetchOEmbed(cache, endpoint, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	m := rxIframeSrc.FindStringSubmatch(res.HTML)
+	if m == nil {
+		return "", fmt.Errorf("no iframe in oEmbed response for '%s'", uri)
+	}
+	src := html.UnescapeString(m[1])
+	if !strings.HasPrefix(src, srcPrefix) {
+		return "", fmt.Errorf("unexpected iframe url '%s' for '%s'", src, uri)
+	}
+	title := res.Title
+	if title == "" {
+		title = uri
+	}
+	s := fmt.Sprintf(`<iframe class="embed-iframe" src="%s" title="%s" height="%d" loading="lazy" allowfullscreen></iframe>`, html.EscapeString(src), html.EscapeString(title), embedHeight(res.Height))
+	return s, nil
+}
+
+type gistFile struct {
+	Filename string `json:"filename"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+type gistInfo struct {
+	HTMLURL     string               `json:"html_url"`
+	Description string               `json:"description"`
+	Files       map[string]*gistFile `json:"files"`
+}
+
+// gistID returns id of a gist from https://gist.github.com/${user}/${id}
+func gistID(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host != "gist.github.com" {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	id := strings.TrimSuffix(parts[len(parts)-1], ".js")
+	if !rxGistID.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+// gistEmbedHTML returns highlighted code of all files in a gist
+func gistEmbedHTML(cache *HTTPDiskCache, uri string) (string, error) {
+	id := gistID(uri)
+	if id == "" {
+		return "", fmt.Errorf("'%s' is not a gist url", uri)
+	}
+	d, _, err := cache.Download(githubAPIURL + "/gists/" + id)
+	if err != nil {
+		return "", err
+	}
+	var gist gistInfo
+	if err = json.Unmarshal(d, &gist); err != nil {
+		return "", err
+	}
+	var names []string
+	for name := range gist.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	title := gist.Description
+	if title == "" {
+		title = "gist " + id
+	}
+	fmt.Fprintf(&buf, `<div class="gist-card"><div class="gist-header"><a href="%s">%s</a></div>`, html.EscapeString(gist.HTMLURL), html.EscapeString(title))
+	for _, name := range names {
+		f := gist.Files[name]
+		fmt.Fprintf(&buf, `<div class="gist-file"><div class="gist-file-name">%s</div>`, html.EscapeString(f.Filename))
+		if err = htmlHighlight(&buf, f.Content, f.Language, ""); err != nil {
+			return "", err
+		}
+		buf.WriteString(`</div>`)
+	}
+	buf.WriteString(`</div>`)
+	return buf.String(), nil
+}
+
+// isGoogleMapsURL returns true for https urls of Google Maps pages,
+// e.g. https://www.google.com/maps/place/... or https://maps.google.com/?q=...
+func isGoogleMapsURL(u *url.URL) bool {
+	if u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	if u.Path == "/maps" || strings.HasPrefix(u.Path, "/maps/") {
+		return host == "maps.google.com" || rxGoogleHost.MatchString(host)
+	}
+	// maps.google.com/?q=... is the only maps url outside of /maps
+	return host == "maps.google.com" && (u.Path == "" || u.Path == "/")
+}
+
+// mapsEmbedHTML converts Google Maps url to lazy-loaded embed iframe.
+// Doesn't need network
+func mapsEmbedHTML(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || !isGoogleMapsURL(u) {
+		return "", fmt.Errorf("'%s' is not a Google Maps url", uri)
+	}
+	src := ""
+	if strings.HasPrefix(u.Path, "/maps/embed") {
+		src = u.String()
+	} else if q := u.Query().Get("q"); q != "" {
+		src = "https://maps.google.com/maps?output=embed&q=" + url.QueryEscape(q)
+	} else if strings.HasPrefix(u.Path, "/maps/place/") {
+		place := strings.Split(strings.TrimPrefix(u.Path, "/maps/place/"), "/")[0]
+		place, _ = url.PathUnescape(strings.Replace(place, "+", " ", -1))
+		src = "https://maps.google.com/maps?output=embed&q=" + url.QueryEscape(place)
+	} else if m := rxMapsCoord.FindStringSubmatch(u.Path); m != nil {
+		src = "https://maps.google.com/maps?output=embed&q=" + url.QueryEscape(m[1]+","+m[2])
+	}
+	if src == "" {
+		return "", fmt.Errorf("don't know how to embed '%s'", uri)
+	}
+	s := fmt.Sprintf(`<iframe class="embed-iframe" src="%s" title="Map" height="%d" loading="lazy" referrerpolicy="no-referrer"></iframe>`, html.EscapeString(src), embedIframeDefaultHeight)
+	return s, nil
+}
+
+// RenderEmbed renders tweets, gists, Codepen, Figma and Maps. Returns
+// false, i.e. renders a link, if we don't have data for the embed
+func (c *Converter) RenderEmbed(block *notionapi.Block) bool {
+	uri := block.Source
+	var s, kind string
+	var err error
+	switch block.Type {
+	case notionapi.BlockTweet:
+		kind = "tweet"
+		s, err = tweetEmbedHTML(c.embeds, uri)
+	case notionapi.BlockGist:
+		kind = "gist"
+		s, err = gistEmbedHTML(c.embeds, uri)
+	case notionapi.BlockCodepen:
+		kind = "codepen"
+		s, err = iframeEmbedHTML(c.embeds, oembedCodepenURL, uri, "https://codepen.io/")
+	case notionapi.BlockFigma:
+		kind = "figma"
+		s, err = iframeEmbedHTML(c.embeds, oembedFigmaURL, uri, "https://www.figma.com/")
+	case notionapi.BlockMaps:
+		kind = "maps"
+		s, err = mapsEmbedHTML(uri)
+	default:
+		return false
+	}
+	if err == errNotCached {
+		logvf("RenderEmbed: '%s' not cached, re-import from Notion\n", uri)
+		return false
+	}
+	if err != nil {
+		logf(ctx(), "RenderEmbed: embedding '%s' failed with '%s'\n", uri, err)
+		return false
+	}
+	c.r.Printf(`<figure id="%s" class="embed embed-%s">`, block.ID, kind)
+	c.r.Printf(`%s`, s)
+	c.r.RenderCaption(block)
+	c.r.Printf(`</figure>`)
+	return true
+}
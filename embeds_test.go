@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func startEmbedsStubServer(t *testing.T) (*httptest.Server, *int) {
+	nRequests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/twitter/oembed", func(w http.ResponseWriter, r *http.Request) {
+		nRequests++
+		assert.Equal(t, "true", r.URL.Query().Get("omit_script"))
+		w.Write([]byte(`{"type":"rich","author_name":"Go","html":"<blockquote class=\"twitter-tweet\"><p>Go 1.19 is released!</p>&mdash; Go (@golang) <a href=\"https://twitter.com/golang/status/1\">August 2, 2022</a></blockquote>\n<script async src=\"https://platform.twitter.com/widgets.js\"></script>"}`))
+	})
+	mux.HandleFunc("/codepen/oembed", func(w http.ResponseWriter, r *http.Request) {
+		nRequests++
+		w.Write([]byte(`{"type":"rich","title":"Pen","height":300,"html":"<iframe id=\"cp_embed_abc\" src=\"https://codepen.io/user/embed/preview/abc?height=300\" title=\"Pen\"></iframe>"}`))
+	})
+	mux.HandleFunc("/evil/oembed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"rich","html":"<iframe src=\"https://evil.example.com/\"></iframe>"}`))
+	})
+	mux.HandleFunc("/gists/aa5a315d61ae9438b18d", func(w http.ResponseWriter, r *http.Request) {
+		nRequests++
+		w.Write([]byte(`{"html_url":"https://gist.github.com/aa5a315d61ae9438b18d","description":"hello","files":{"main.go":{"filename":"main.go","language":"Go","content":"package main\n"}}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	prevTwitter, prevCodepen, prevGitHub := oembedTwitterURL, oembedCodepenURL, githubAPIURL
+	oembedTwitterURL = srv.URL + "/twitter/oembed"
+	oembedCodepenURL = srv.URL + "/codepen/oembed"
+	githubAPIURL = srv.URL
+	t.Cleanup(func() {
+		oembedTwitterURL, oembedCodepenURL, githubAPIURL = prevTwitter, prevCodepen, prevGitHub
+	})
+	return srv, &nRequests
+}
+
+func TestTweetEmbed(t *testing.T) {
+	_, nRequests := startEmbedsStubServer(t)
+	dir := t.TempDir()
+	cache := NewHTTPDiskCache(dir, false)
+	s, err := tweetEmbedHTML(cache, "https://twitter.com/golang/status/1")
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(s, "Go 1.19 is released!"))
+	assert.False(t, strings.Contains(s, "<script"))
+	assert.Equal(t, 1, *nRequests)
+
+	// in cache-only mode we use what was downloaded before
+	cache = NewHTTPDiskCache(dir, true)
+	s2, err := tweetEmbedHTML(cache, "https://twitter.com/golang/status/1")
+	assert.NoError(t, err)
+	assert.Equal(t, s, s2)
+	assert.Equal(t, 1, *nRequests)
+
+	_, err = tweetEmbedHTML(cache, "https://twitter.com/golang/status/2")
+	assert.Equal(t, errNotCached, err)
+}
+
+func TestIframeEmbed(t *testing.T) {
+	srv, _ := startEmbedsStubServer(t)
+	cache := NewHTTPDiskCache(t.TempDir(), false)
+	s, err := iframeEmbedHTML(cache, oembedCodepenURL, "https://codepen.io/user/pen/abc", "https://codepen.io/")
+	assert.NoError(t, err)
+	assert.Equal(t, `<iframe class="embed-iframe" src="https://codepen.io/user/embed/preview/abc?height=300" title="Pen" height="300" loading="lazy" allowfullscreen></iframe>`, s)
+
+	_, err = iframeEmbedHTML(cache, srv.URL+"/evil/oembed", "https://codepen.io/user/pen/abc", "https://codepen.io/")
+	assert.Error(t, err)
+}
+
+func TestGistEmbed(t *testing.T) {
+	startEmbedsStubServer(t)
+	assert.Equal(t, "aa5a315d61ae9438b18d", gistID("https://gist.github.com/kjk/aa5a315d61ae9438b18d"))
+	assert.Equal(t, "", gistID("https://github.com/kjk/blog"))
+
+	cache := NewHTTPDiskCache(t.TempDir(), false)
+	s, err := gistEmbedHTML(cache, "https://gist.github.com/kjk/aa5a315d61ae9438b18d")
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(s, `<a href="https://gist.github.com/aa5a315d61ae9438b18d">hello</a>`))
+	assert.True(t, strings.Contains(s, "main.go"))
+	assert.True(t, strings.Contains(s, "package"))
+}
+
+func TestMapsEmbed(t *testing.T) {
+	s, err := mapsEmbedHTML("https://www.google.com/maps/place/Hanoi,+Vietnam/@21.0227788,105.8194541,12z")
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(s, `src="https://maps.google.com/maps?output=embed&amp;q=Hanoi%2C+Vietnam"`))
+
+	s, err = mapsEmbedHTML("https://www.google.com/maps/@21.0227788,105.8194541,12z")
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(s, `q=21.0227788%2C105.8194541`))
+
+	s, err = mapsEmbedHTML("https://maps.google.com/?q=Hanoi")
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(s, `q=Hanoi"`))
+
+	s, err = mapsEmbedHTML("https://www.google.co.uk/maps/embed?pb=!1m18")
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(s, `src="https://www.google.co.uk/maps/embed?pb=!1m18"`))
+
+	invalid := []string{
+		"https://example.com/maps",
+		"https://google.evil.com/maps/embed?pb=1",
+		"https://evilgoogle.com/maps/embed?pb=1",
+		"https://www.google.com.evil.com/maps/embed?pb=1",
+		"http://www.google.com/maps/embed?pb=1",
+		"javascript://www.google.com/maps/embed?pb=1",
+		"https://www.google.com/search?q=Hanoi",
+		"https://www.google.com/?q=Hanoi",
+	}
+	for _, uri := range invalid {
+		_, err = mapsEmbedHTML(uri)
+		assert.Error(t, err)
+	}
+}
@@ -1,10 +1,16 @@
 package entity
 
+// BlockExtend has values of Notion database columns of a page
 type BlockExtend struct {
 	Slug  string
 	Title string
 	//StartDate string
-	Tags   []string
-	Type   string
-	Status string
+	Tags        []string
+	Type        string
+	Status      string
+	PublishedOn string
+	Description string
+	Cover       string
+	Series      string
+	SeriesOrder string
 }
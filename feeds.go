@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// /rss.xml and /feed.json, with the same articles as /atom.xml
+
+type rssFeed struct {
+	XMLName      xml.Name   `xml:"rss"`
+	Version      string     `xml:"version,attr"`
+	NsAtom       string     `xml:"xmlns:atom,attr"`
+	NsContent    string     `xml:"xmlns:content,attr"`
+	NsDublinCore string     `xml:"xmlns:dc,attr"`
+	Channel      rssChannel `xml:"channel"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	SelfLink      rssAtomLink `xml:"atom:link"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	Items         []*rssItem  `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description,omitempty"`
+	Content     *rssCDATA     `xml:"content:encoded"`
+	Creator     string        `xml:"dc:creator,omitempty"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+// JSONFeed is JSON Feed 1.1 (https://jsonfeed.org/version/1.1)
+type JSONFeed struct {
+	Version     string            `json:"version"`
+	Title       string            `json:"title"`
+	HomePageURL string            `json:"home_page_url"`
+	FeedURL     string            `json:"feed_url"`
+	Description string            `json:"description,omitempty"`
+	Authors     []*JSONFeedAuthor `json:"authors,omitempty"`
+	Items       []*JSONFeedItem   `json:"items"`
+}
+
+// JSONFeedAuthor is an author in JSON Feed
+type JSONFeedAuthor struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// JSONFeedAttachment is an attachment in JSON Feed
+type JSONFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+}
+
+// JSONFeedItem is an item in JSON Feed
+type JSONFeedItem struct {
+	ID            string                `json:"id"`
+	URL           string                `json:"url"`
+	Title         string                `json:"title"`
+	ContentHTML   string                `json:"content_html"`
+	Summary       string                `json:"summary,omitempty"`
+	Image         string                `json:"image,omitempty"`
+	DatePublished string                `json:"date_published"`
+	DateModified  string                `json:"date_modified,omitempty"`
+	Authors       []*JSONFeedAuthor     `json:"authors,omitempty"`
+	Tags          []string              `json:"tags,omitempty"`
+	Attachments   []*JSONFeedAttachment `json:"attachments,omitempty"`
+	// extension, see https://www.jsonfeed.org/version/1.1/#extensions-a-name-extensions-a
+	Reading *JSONFeedReading `json:"_reading,omitempty"`
+}
+
+// JSONFeedReading is our JSON Feed extension with reading stats
+type JSONFeedReading struct {
+	WordCount          int `json:"word_count"`
+	CodeWordCount      int `json:"code_word_count,omitempty"`
+	ReadingTimeMinutes int `json:"reading_time_minutes"`
+}
+
+func feedDescription() string {
+	if siteConfig.Description != "" {
+		return siteConfig.Description
+	}
+	return siteConfig.Title
+}
+
+// coverImageSize returns size of a locally stored cover image or 0
+func coverImageSize(uri string) int64 {
+	var path string
+	if strings.HasPrefix(uri, "/img/") {
+		path = filepath.Join(cacheDir, "files", strings.TrimPrefix(uri, "/img/"))
+	} else {
+		path = filepath.Join("www", filepath.FromSlash(strings.TrimPrefix(uri, "/")))
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return st.Size()
+}
+
+func genRSSXML(store *Articles, excludeNotes bool) ([]byte, error) {
+	latest := feedArticles(store, excludeNotes)
+	author := siteConfig.Author.Name
+
+	channel := rssChannel{
+		Title:       siteConfig.Title,
+		Link:        getHostURL() + "/",
+		Description: feedDescription(),
+		SelfLink: rssAtomLink{
+			Href: getHostURL() + "/rss.xml",
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+	}
+	if len(latest) > 0 {
+		channel.LastBuildDate = latest[0].PublishedOn.Format(time.RFC1123Z)
+	}
+	for _, a := range latest {
+		uri := getHostURL() + a.URL()
+		item := &rssItem{
+			Title: a.Title,
+			Link:  uri,
+			GUID: rssGUID{
+				IsPermaLink: true,
+				Value:       uri,
+			},
+			PubDate:     a.PublishedOn.Format(time.RFC1123Z),
+			Description: articleSummary(a, htmlToText(a.BodyHTML)),
+			Content:     &rssCDATA{a.BodyHTML},
+			Creator:     author,
+			Categories:  a.Tags,
+		}
+		if a.HeaderImageURL != "" {
+			item.Enclosure = &rssEnclosure{
+				URL:    getHostURL() + a.HeaderImageURL,
+				Length: coverImageSize(a.HeaderImageURL),
+				Type:   mimeTypeFromFileName(a.HeaderImageURL),
+			}
+		}
+		channel.Items = append(channel.Items, item)
+	}
+
+	feed := &rssFeed{
+		Version:      "2.0",
+		NsAtom:       "http://www.w3.org/2005/Atom",
+		NsContent:    "http://purl.org/rss/1.0/modules/content/",
+		NsDublinCore: "http://purl.org/dc/elements/1.1/",
+		Channel:      channel,
+	}
+	d, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), d...), nil
+}
+
+func genJSONFeed(store *Articles, excludeNotes bool) ([]byte, error) {
+	latest := feedArticles(store, excludeNotes)
+	var authors []*JSONFeedAuthor
+	if siteConfig.Author.Name != "" {
+		author := &JSONFeedAuthor{
+			Name: siteConfig.Author.Name,
+			URL:  siteConfig.Author.URL,
+		}
+		authors = append(authors, author)
+	}
+
+	feed := &JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       siteConfig.Title,
+		HomePageURL: getHostURL() + "/",
+		FeedURL:     getHostURL() + "/feed.json",
+		Description: feedDescription(),
+		Authors:     authors,
+		Items:       []*JSONFeedItem{},
+	}
+	for _, a := range latest {
+		uri := getHostURL() + a.URL()
+		item := &JSONFeedItem{
+			ID:            uri,
+			URL:           uri,
+			Title:         a.Title,
+			ContentHTML:   a.BodyHTML,
+			Summary:       articleSummary(a, htmlToText(a.BodyHTML)),
+			DatePublished: a.PublishedOn.Format(time.RFC3339),
+			Authors:       authors,
+			Tags:          a.Tags,
+			Reading: &JSONFeedReading{
+				WordCount:          a.WordCount,
+				CodeWordCount:      a.CodeWordCount,
+				ReadingTimeMinutes: a.ReadingTime,
+			},
+		}
+		if !a.UpdatedOn.IsZero() {
+			item.DateModified = a.UpdatedOn.Format(time.RFC3339)
+		}
+		if a.HeaderImageURL != "" {
+			item.Image = getHostURL() + a.HeaderImageURL
+			att := &JSONFeedAttachment{
+				URL:         item.Image,
+				MimeType:    mimeTypeFromFileName(a.HeaderImageURL),
+				SizeInBytes: coverImageSize(a.HeaderImageURL),
+			}
+			item.Attachments = append(item.Attachments, att)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return json.MarshalIndent(feed, "", "  ")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func makeFeedTestStore() *Articles {
+	t1 := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	a1 := &Article{
+		ID:             "a1",
+		Title:          "Xin chào",
+		Slug:           "xin-chao",
+		Tags:           []string{"go", "web"},
+		Summary:        "Short summary",
+		BodyHTML:       "<p>Hello <b>world</b></p>",
+		HeaderImageURL: "/img/cover.png",
+		PublishedOn:    t1,
+		UpdatedOn:      t1.Add(time.Hour),
+		inBlog:         true,
+	}
+	a2 := &Article{
+		ID:          "a2",
+		Title:       "A note",
+		Tags:        []string{"note"},
+		BodyHTML:    "<p>note</p>",
+		PublishedOn: t1.Add(24 * time.Hour),
+		inBlog:      true,
+	}
+	return &Articles{
+		articles: []*Article{a1, a2},
+		blog:     []*Article{a1, a2},
+	}
+}
+
+func TestGenRSSXML(t *testing.T) {
+	d, err := genRSSXML(makeFeedTestStore(), true)
+	assert.NoError(t, err)
+	s := string(d)
+	assert.True(t, strings.Contains(s, "<content:encoded><![CDATA[<p>Hello <b>world</b></p>]]></content:encoded>"))
+	assert.True(t, strings.Contains(s, `<enclosure url="https://ntheanh201.vercel.app/img/cover.png" length="0" type="image/png"></enclosure>`))
+
+	var rss struct {
+		Channel struct {
+			Items []struct {
+				Title       string   `xml:"title"`
+				Link        string   `xml:"link"`
+				PubDate     string   `xml:"pubDate"`
+				Description string   `xml:"description"`
+				Categories  []string `xml:"category"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	assert.NoError(t, xml.Unmarshal(d, &rss))
+	items := rss.Channel.Items
+	// notes are excluded
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "Xin chào", items[0].Title)
+	assert.Equal(t, "https://ntheanh201.vercel.app/articles/xin-chao.html", items[0].Link)
+	assert.Equal(t, "Fri, 04 Mar 2022 05:06:07 +0000", items[0].PubDate)
+	assert.Equal(t, "Short summary", items[0].Description)
+	assert.Equal(t, []string{"go", "web"}, items[0].Categories)
+}
+
+func TestGenJSONFeed(t *testing.T) {
+	d, err := genJSONFeed(makeFeedTestStore(), false)
+	assert.NoError(t, err)
+	var feed JSONFeed
+	assert.NoError(t, json.Unmarshal(d, &feed))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", feed.Version)
+	assert.Equal(t, 2, len(feed.Items))
+	// newest first
+	assert.Equal(t, "A note", feed.Items[0].Title)
+	item := feed.Items[1]
+	assert.Equal(t, "2022-03-04T05:06:07Z", item.DatePublished)
+	assert.Equal(t, "2022-03-04T06:06:07Z", item.DateModified)
+	assert.Equal(t, "https://ntheanh201.vercel.app/img/cover.png", item.Image)
+	assert.Equal(t, "image/png", item.Attachments[0].MimeType)
+	assert.Equal(t, siteConfig.Author.Name, item.Authors[0].Name)
+}
@@ -9,9 +9,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
 
-	atom "github.com/thomas11/atomgenerator"
+	"github.com/ntheanh201/blog/tohtml"
 )
 
 func copyAndSortArticles(articles []*Article) []*Article {
@@ -24,11 +23,8 @@ func copyAndSortArticles(articles []*Article) []*Article {
 	return res
 }
 
-func genAtomXML(store *Articles, excludeNotes bool) ([]byte, error) {
-	articles := store.getBlogNotHidden()
-	if excludeNotes {
-		articles = filterArticlesByTag(articles, "note", false)
-	}
+// latestArticles returns up to 25 newest articles, newest first
+func latestArticles(articles []*Article) []*Article {
 	articles = copyAndSortArticles(articles)
 	n := 25
 	if n > len(articles) {
@@ -40,30 +36,22 @@ func genAtomXML(store *Articles, excludeNotes bool) ([]byte, error) {
 	for i := 0; i < n; i++ {
 		latest[i] = articles[size-1-i]
 	}
+	return latest
+}
 
-	pubTime := time.Now()
-	if len(articles) > 0 {
-		pubTime = articles[0].PublishedOn
-	}
-
-	feed := &atom.Feed{
-		Title:   "The Anh Nguyen blog",
-		Link:    "https://ntheanh201.vercel.app/atom.xml",
-		PubDate: pubTime,
-	}
-
-	for _, a := range latest {
-		//id := fmt.Sprintf("tag:blog.kowalczyk.info,1999:%d", a.Id)
-		e := &atom.Entry{
-			Title:   a.Title,
-			Link:    "https://ntheanh201.vercel.app" + a.URL(),
-			Content: a.BodyHTML,
-			PubDate: a.PublishedOn,
-		}
-		feed.AddEntry(e)
+// feedArticles returns latest blog articles for feeds, newest first.
+// If excludeNotes is true, articles tagged "note" are skipped
+func feedArticles(store *Articles, excludeNotes bool) []*Article {
+	articles := store.getBlogNotHidden()
+	if excludeNotes {
+		articles = filterArticlesByTag(articles, "note", false)
 	}
+	return latestArticles(articles)
+}
 
-	return feed.GenXml()
+func genAtomXML(store *Articles, excludeNotes bool) ([]byte, error) {
+	latest := feedArticles(store, excludeNotes)
+	return genAtomXMLForArticles(latest, siteConfig.Title, "/atom.xml")
 }
 
 func wwwPath(fileName string) string {
@@ -79,11 +67,6 @@ func wwwWriteFile(fileName string, d []byte) {
 	ioutil.WriteFile(path, d, 0644)
 }
 
-// TODO: should be https://blog.kjk.workers.dev for dev deployment
-func getHostURL() string {
-	return "https://ntheanh201.vercel.app"
-}
-
 // https://www.linkedin.com/shareArticle?mini=true&;url=https://nodesource.com/blog/why-the-new-v8-is-so-damn-fast"
 func makeLinkedinShareURL(article *Article) string {
 	uri := getHostURL() + article.URL()
@@ -98,12 +81,16 @@ func makeFacebookShareURL(article *Article) string {
 	return fmt.Sprintf(`https://www.facebook.com/sharer/sharer.php?u=%s`, uri)
 }
 
-// https://twitter.com/intent/tweet?text=%s&url=%s&via=kjk
+// https://twitter.com/intent/tweet?text=%s&url=%s&via=ntheanh201
 func makeTwitterShareURL(article *Article) string {
 	title := url.QueryEscape(article.Title)
 	uri := getHostURL() + article.URL()
 	uri = url.QueryEscape(uri)
-	return fmt.Sprintf(`https://twitter.com/intent/tweet?text=%s&url=%s&via=kjk`, title, uri)
+	res := fmt.Sprintf(`https://twitter.com/intent/tweet?text=%s&url=%s`, title, uri)
+	if siteConfig.TwitterHandle != "" {
+		res += "&via=" + url.QueryEscape(siteConfig.TwitterHandle)
+	}
+	return res
 }
 
 // TagInfo represents a single tag for articles
@@ -154,43 +141,32 @@ func buildTags(articles []*Article) []*TagInfo {
 	return res
 }
 
-func writeArticlesArchiveForTag(store *Articles, tag string, w io.Writer) error {
-	path := "/archives.html"
-	articles := append([]*Article{}, store.articles...)
-
-	var pagesSite []*Article
-	var posts []*Article
-	for _, article := range articles {
-		if article.Type == "Page" {
-			pagesSite = append(pagesSite, article)
-		} else {
-			posts = append(posts, article)
-		}
-	}
-
-	articles = posts
-
-	if tag != "" {
-		articles = filterArticlesByTag(articles, tag, true)
-		// must manually resolve conflict due to urlify
-		tagInPath := tag
-		tagInPath = urlify(tagInPath)
-		path = fmt.Sprintf("/articles/archives-by-tag-%s.html", tagInPath)
-		from := "/tag/" + tag
-		addRewrite(from, path)
-	}
+// writeArticlesArchiveForTag writes page (1-based) of archive for a tag
+// or of all articles if tag is ""
+func writeArticlesArchiveForTag(store *Articles, tag string, page int, w io.Writer) error {
+	articles := archiveArticles(store, tag)
+	firstURL := archiveURL(tag)
+	path := pageURL(firstURL, page)
 
+	pageSize := siteConfig.Pagination.ArchivePageSize
+	pager := makePager(firstURL, page, numPages(len(articles), pageSize))
 	model := struct {
 		Article    *Article
 		PostsCount int
 		Tag        string
 		Years      []Year
 		Tags       []*TagInfo
+		Pager      *Pager
+		FeedURL    string
 	}{
 		PostsCount: len(articles),
-		Years:      buildYearsFromArticles(articles),
+		Years:      buildYearsFromArticles(paginate(articles, page, pageSize)),
 		Tag:        tag,
 		Tags:       buildTags(articles),
+		Pager:      pager,
+	}
+	if tag != "" {
+		model.FeedURL = topicFeedURL(firstURL)
 	}
 
 	return execTemplate(path, "archive.tmpl.html", model, w)
@@ -202,49 +178,33 @@ func (a ByType) Len() int           { return len(a) }
 func (a ByType) Less(i, j int) bool { return a[i].Type > a[j].Type }
 func (a ByType) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
-func genIndex(store *Articles, w io.Writer) error {
-	//articles := store.articles
-	//articles := store.getBlogNotHidden()
-	//if len(articles) > 5 {
-	//	articles = articles[:5]
-	//}
-
-	articles := append([]*Article{}, store.articles...)
-
-	sort.Sort(ByType(articles))
-
-	var pagesSite []*Article
-	var posts []*Article
-	for _, article := range articles {
-		if article.Type == "Page" {
-			pagesSite = append(pagesSite, article)
-		} else {
-			posts = append(posts, article)
-		}
-	}
-
-	articles = posts
-
-	sort.Slice(articles, func(i, j int) bool {
-		a1 := articles[i]
-		a2 := articles[j]
-		return a1.PublishedOn.After(a2.PublishedOn)
-	})
+// genIndex writes page (1-based) of the index
+func genIndex(store *Articles, page int, w io.Writer) error {
+	articles, pagesSite := splitPosts(store.articles)
+	sortNewestFirst(articles)
 
 	sort.Slice(pagesSite, func(i, j int) bool {
-		a1 := articles[i]
-		a2 := articles[j]
+		a1 := pagesSite[i]
+		a2 := pagesSite[j]
 		return a2.UpdatedOn.After(a1.UpdatedOn)
 	})
 
 	articleCount := len(articles)
-	//websiteIndexPage := store.idToArticle[notionWebsiteStartPage]
+	pageSize := siteConfig.Pagination.IndexPageSize
+	pager := makePager("/index.html", page, numPages(articleCount, pageSize))
+	articles = paginate(articles, page, pageSize)
+	if page > 1 {
+		// pages are only listed on the first page
+		pagesSite = nil
+	}
+	//websiteIndexPage := store.idToArticle[siteConfig.Notion.WebsiteStartPage]
 	model := struct {
 		Article      *Article
 		Articles     []*Article
 		PagesSite    []*Article
 		ArticleCount int
 		WebsiteHTML  template.HTML
+		Pager        *Pager
 	}{
 		Article:      nil, // always nil
 		ArticleCount: articleCount,
@@ -252,8 +212,9 @@ func genIndex(store *Articles, w io.Writer) error {
 		PagesSite:    pagesSite,
 		//WebsiteHTML:  websiteIndexPage.HTMLBody,
 		WebsiteHTML: "<></>",
+		Pager:       pager,
 	}
-	return execTemplate("/index.html", "mainpage.tmpl.html", model, w)
+	return execTemplate(pageURL("/index.html", page), "mainpage.tmpl.html", model, w)
 }
 
 func genChangelog(store *Articles, w io.Writer) error {
@@ -298,8 +259,23 @@ func gen404(store *Articles, w io.Writer) error {
 	return execTemplate("/404.html", "404.tmpl.html", model, w)
 }
 
+func genSearch(store *Articles, w io.Writer) error {
+	// store is not used, search.html loads /search-index.json
+	model := struct {
+		Article *Article
+		// used by normalize() in search.tmpl.html
+		Transliterations map[string]string
+	}{
+		Transliterations: tohtml.Transliterations,
+	}
+	return execTemplate("/search.html", "search.tmpl.html", model, w)
+}
+
 func genArticle(article *Article, w io.Writer) error {
 	canonicalURL := getHostURL() + article.URL()
+	if article.IsPreview() {
+		canonicalURL = getHostURL() + article.PreviewURL()
+	}
 	model := struct {
 		Article          *Article
 		CanonicalURL     string
@@ -314,10 +290,15 @@ func genArticle(article *Article, w io.Writer) error {
 		FacebookShareURL string
 		LinkedInShareURL string
 		ShowSocialFooter bool
+		Series           *Series
+		SeriesPart       int
+		PrevInSeries     *Article
+		NextInSeries     *Article
+		StructuredData   template.JS
 	}{
 		Article:          article,
 		CanonicalURL:     canonicalURL,
-		CoverImage:       "https://ntheanh201.vercel.app" + article.HeaderImageURL,
+		CoverImage:       getHostURL() + article.HeaderImageURL,
 		PageTitle:        article.Title,
 		Description:      article.Description,
 		Summary:          article.Summary,
@@ -325,6 +306,18 @@ func genArticle(article *Article, w io.Writer) error {
 		FacebookShareURL: makeFacebookShareURL(article),
 		LinkedInShareURL: makeLinkedinShareURL(article),
 		ShowSocialFooter: article.Type == "Post",
+		StructuredData:   articleStructuredData(article, canonicalURL),
+	}
+	if s := article.series; s != nil {
+		idx := s.partIndex(article)
+		model.Series = s
+		model.SeriesPart = idx + 1
+		if idx > 0 {
+			model.PrevInSeries = s.Articles[idx-1]
+		}
+		if idx+1 < len(s.Articles) {
+			model.NextInSeries = s.Articles[idx+1]
+		}
 	}
 	if article.page != nil {
 		id := normalizeID(article.page.ID)
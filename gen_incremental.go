@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kjk/common/server"
+)
+
+// name of the file, inside generated directory, that remembers what
+// we generated the last time
+const genManifestName = ".gen_manifest.json"
+
+type genManifestEntry struct {
+	// sha1 of the content
+	Hash string `json:"hash"`
+	// for articles: id of Notion page, when it was last edited and hash
+	// of everything else the article depends on (templates, urls and
+	// titles of other articles)
+	PageID       string    `json:"pageId,omitempty"`
+	LastEditedOn time.Time `json:"lastEditedOn,omitempty"`
+	DepsHash     string    `json:"depsHash,omitempty"`
+}
+
+type genManifest struct {
+	// maps url to info about generated file
+	Files map[string]*genManifestEntry `json:"files"`
+}
+
+func readGenManifest(dir string) *genManifest {
+	res := &genManifest{
+		Files: map[string]*genManifestEntry{},
+	}
+	path := filepath.Join(dir, genManifestName)
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return res
+	}
+	if err = json.Unmarshal(d, res); err != nil {
+		logerrf(ctx(), "readGenManifest: json.Unmarshal of '%s' failed with '%s'\n", path, err)
+		return &genManifest{Files: map[string]*genManifestEntry{}}
+	}
+	return res
+}
+
+func writeGenManifest(dir string, m *genManifest) {
+	d, err := json.MarshalIndent(m, "", "  ")
+	must(err)
+	path := filepath.Join(dir, genManifestName)
+	must(ioutil.WriteFile(path, d, 0644))
+}
+
+func sha1HexOf(d []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(d))
+}
+
+// articlesDepsHash changes when anything that might affect html of
+// an article, other than the article's own Notion page, changes
+func articlesDepsHash(articles []*Article) string {
+	h := sha1.New()
+	arr := append([]*Article{}, articles...)
+	sort.Slice(arr, func(i, j int) bool {
+		return arr[i].ID < arr[j].ID
+	})
+	for _, a := range arr {
+		fmt.Fprintf(h, "%s\t%s\t%s\t%s\t%s\t%d\n", a.ID, a.URL(), a.Title, strings.Join(a.Tags, ","), a.Series, a.SeriesOrder)
+		// related articles depend on text of other articles
+		for _, related := range a.RelatedArticles {
+			fmt.Fprintf(h, "\t%s", related.ID)
+		}
+		// links to headers of other articles use their anchors
+		var blockIDs []string
+		for id := range a.headerAnchors {
+			blockIDs = append(blockIDs, id)
+		}
+		sort.Strings(blockIDs)
+		for _, id := range blockIDs {
+			fmt.Fprintf(h, "\t%s=%s", id, a.headerAnchors[id])
+		}
+		for _, e := range a.TOC {
+			fmt.Fprintf(h, "\t%d:%s", e.Level, e.Anchor)
+		}
+		h.Write([]byte("\n"))
+	}
+	tmplFiles, _ := filepath.Glob(filepath.Join("www", "tmpl", "*.tmpl.html"))
+	sort.Strings(tmplFiles)
+	for _, path := range tmplFiles {
+		d, _ := ioutil.ReadFile(path)
+		fmt.Fprintf(h, "%s\t%s\n", path, sha1HexOf(d))
+	}
+	d, _ := json.Marshal(siteConfig)
+	h.Write(d)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// bufResponseWriter captures the output of a handler
+type bufResponseWriter struct {
+	buf    bytes.Buffer
+	header http.Header
+}
+
+func (w *bufResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *bufResponseWriter) Write(d []byte) (int, error) {
+	return w.buf.Write(d)
+}
+
+func (w *bufResponseWriter) WriteHeader(statusCode int) {
+}
+
+func renderURL(h server.Handler, uri string) []byte {
+	serve := h.Get(uri)
+	panicIf(serve == nil, "must have a handler for '%s'", uri)
+	w := &bufResponseWriter{}
+	serve(w, nil)
+	return w.buf.Bytes()
+}
+
+// GenStats describes what changed in generated directory
+type GenStats struct {
+	Added     []string
+	Changed   []string
+	Removed   []string
+	Unchanged int
+}
+
+func logGenStats(stats *GenStats) {
+	logList := func(name string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		logf(ctx(), "%s:\n", name)
+		for i, path := range paths {
+			if i == 32 && !flgVerbose {
+				logf(ctx(), "  ... and %d more\n", len(paths)-i)
+				break
+			}
+			logf(ctx(), "  %s\n", path)
+		}
+	}
+	logList("added", stats.Added)
+	logList("changed", stats.Changed)
+	logList("removed", stats.Removed)
+	logf(ctx(), "generate: %d added, %d changed, %d removed, %d unchanged\n", len(stats.Added), len(stats.Changed), len(stats.Removed), stats.Unchanged)
+}
+
+// writeServerFilesIncremental writes content of all urls to dir, skipping
+// articles whose Notion page didn't change since last time and files whose
+// content didn't change. Removes files that are no longer generated
+func writeServerFilesIncremental(dir string, handlers []server.Handler, urlToArticle map[string]*Article, depsHash string) *GenStats {
+	stats := &GenStats{}
+	prev := readGenManifest(dir)
+	curr := &genManifest{
+		Files: map[string]*genManifestEntry{},
+	}
+	for _, h := range handlers {
+		for _, uri := range h.URLS() {
+			if curr.Files[uri] != nil {
+				// first handler wins, like in server.FindHandler()
+				continue
+			}
+			name := filepath.FromSlash(strings.TrimPrefix(uri, "/"))
+			path := filepath.Join(dir, name)
+			prevEntry := prev.Files[uri]
+			entry := &genManifestEntry{}
+			if a := urlToArticle[uri]; a != nil {
+				entry.PageID = a.ID
+				entry.LastEditedOn = a.UpdatedOn
+				entry.DepsHash = depsHash
+				isSame := prevEntry != nil && prevEntry.PageID == entry.PageID && prevEntry.LastEditedOn.Equal(entry.LastEditedOn) && prevEntry.DepsHash == depsHash
+				if isSame && fileExists(path) {
+					entry.Hash = prevEntry.Hash
+					curr.Files[uri] = entry
+					stats.Unchanged++
+					continue
+				}
+			}
+
+			d := renderURL(h, uri)
+			entry.Hash = sha1HexOf(d)
+			curr.Files[uri] = entry
+			if prevEntry != nil && prevEntry.Hash == entry.Hash && fileExists(path) {
+				stats.Unchanged++
+				continue
+			}
+			if fileExists(path) {
+				stats.Changed = append(stats.Changed, uri)
+			} else {
+				stats.Added = append(stats.Added, uri)
+			}
+			must(createDirForFile(path))
+			must(ioutil.WriteFile(path, d, 0644))
+		}
+	}
+
+	// remove files we no longer generate
+	filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		must(err)
+		uri := "/" + filepath.ToSlash(rel)
+		if uri == "/"+genManifestName || curr.Files[uri] != nil {
+			return nil
+		}
+		must(os.Remove(path))
+		stats.Removed = append(stats.Removed, uri)
+		return nil
+	})
+
+	writeGenManifest(dir, curr)
+	sort.Strings(stats.Added)
+	sort.Strings(stats.Changed)
+	sort.Strings(stats.Removed)
+	return stats
+}
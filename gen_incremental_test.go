@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/common/server"
+)
+
+func TestWriteServerFilesIncremental(t *testing.T) {
+	dir := t.TempDir()
+	article := &Article{ID: "a1", UpdatedOn: time.Unix(1000, 0)}
+	content := map[string]string{
+		"/index.html":       "index",
+		"/articles/a1.html": "article",
+	}
+	nRendered := 0
+	get := func(uri string) func(w http.ResponseWriter, r *http.Request) {
+		s, ok := content[uri]
+		if !ok {
+			return nil
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			nRendered++
+			w.Write([]byte(s))
+		}
+	}
+	urls := func() []string {
+		var res []string
+		for uri := range content {
+			res = append(res, uri)
+		}
+		return res
+	}
+	handlers := []server.Handler{server.NewDynamicHandler(get, urls)}
+	urlToArticle := map[string]*Article{"/articles/a1.html": article}
+
+	stats := writeServerFilesIncremental(dir, handlers, urlToArticle, "deps")
+	assert.Equal(t, []string{"/articles/a1.html", "/index.html"}, stats.Added)
+	assert.Equal(t, 2, nRendered)
+
+	// nothing changed, article is not even rendered
+	nRendered = 0
+	stats = writeServerFilesIncremental(dir, handlers, urlToArticle, "deps")
+	assert.Equal(t, 2, stats.Unchanged)
+	assert.Equal(t, 1, nRendered)
+
+	// article was edited in notion
+	article.UpdatedOn = time.Unix(2000, 0)
+	content["/articles/a1.html"] = "article v2"
+	stats = writeServerFilesIncremental(dir, handlers, urlToArticle, "deps")
+	assert.Equal(t, []string{"/articles/a1.html"}, stats.Changed)
+	d, err := os.ReadFile(filepath.Join(dir, "articles", "a1.html"))
+	assert.NoError(t, err)
+	assert.Equal(t, "article v2", string(d))
+
+	// index is no longer generated
+	delete(content, "/index.html")
+	stats = writeServerFilesIncremental(dir, handlers, urlToArticle, "deps")
+	assert.Equal(t, []string{"/index.html"}, stats.Removed)
+	assert.False(t, fileExists(filepath.Join(dir, "index.html")))
+}
+
+func TestArticlesDepsHashHeaderAnchors(t *testing.T) {
+	a := &Article{ID: "a1", headerAnchors: map[string]string{"b1": "gioi-thieu", "b2": "ket-luan"}}
+	b := &Article{ID: "a2"}
+	h1 := articlesDepsHash([]*Article{a, b})
+	assert.Equal(t, h1, articlesDepsHash([]*Article{b, a}))
+
+	// renaming a header of a1 changes links to it from a2
+	a.headerAnchors["b1"] = "mo-dau"
+	h2 := articlesDepsHash([]*Article{a, b})
+	assert.NotEqual(t, h1, h2)
+
+	a.TOC = []*TOCEntry{{Level: 2, Title: "Mở đầu", Anchor: "mo-dau"}}
+	assert.NotEqual(t, h2, articlesDepsHash([]*Article{a, b}))
+}
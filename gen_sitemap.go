@@ -2,62 +2,214 @@ package main
 
 import (
 	"encoding/xml"
-	"path"
+	"fmt"
+	"strings"
 	"time"
 )
 
+// sitemap protocol allows at most 50 000 urls per file
+const siteMapMaxURLs = 50000
+
 // SiteMapURLSet represents <urlset>
 type SiteMapURLSet struct {
 	XMLName xml.Name `xml:"urlset"`
 	Ns      string   `xml:"xmlns,attr"`
+	NsImage string   `xml:"xmlns:image,attr"`
 	URLS    []SiteMapURL
 }
 
 func makeSiteMapURLSet() *SiteMapURLSet {
 	return &SiteMapURLSet{
-		Ns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Ns:      "http://www.sitemaps.org/schemas/sitemap/0.9",
+		NsImage: "http://www.google.com/schemas/sitemap-image/1.1",
 	}
 }
 
 // SiteMapURL represents a single url
 type SiteMapURL struct {
-	XMLName      xml.Name `xml:"url"`
+	XMLName      xml.Name       `xml:"url"`
+	URL          string         `xml:"loc"`
+	LastModified string         `xml:"lastmod"`
+	Images       []SiteMapImage `xml:"image:image"`
+}
+
+// SiteMapImage represents <image:image>
+type SiteMapImage struct {
+	URL string `xml:"image:loc"`
+}
+
+// SiteMapIndex represents <sitemapindex>
+type SiteMapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	Ns       string   `xml:"xmlns,attr"`
+	SiteMaps []SiteMapRef
+}
+
+// SiteMapRef represents <sitemap> in <sitemapindex>
+type SiteMapRef struct {
+	XMLName      xml.Name `xml:"sitemap"`
 	URL          string   `xml:"loc"`
 	LastModified string   `xml:"lastmod"`
 }
 
 // There are more static pages, but those are the important ones
-var staticURLS = []string{}
+var staticURLS = []string{
+	"/changelog.html",
+}
 
-func genSiteMap(store *Articles, host string) ([]byte, error) {
+// urls we don't want crawled. /preview/ is not listed because that
+// would advertise it, preview pages have noindex meta instead
+var robotsDisallow = []string{
+	"/notes/",
+	"/dev/",
+	"/api/",
+}
+
+// joinURL joins host like "https://example.com" and path like "/foo.html"
+func joinURL(host string, uri string) string {
+	return strings.TrimSuffix(host, "/") + "/" + strings.TrimPrefix(uri, "/")
+}
+
+func formatSiteMapDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func articleSiteMapImages(host string, a *Article) []SiteMapImage {
+	var res []SiteMapImage
+	seen := map[string]bool{}
+	add := func(uri string) {
+		if uri == "" || seen[uri] {
+			return
+		}
+		seen[uri] = true
+		if !strings.HasPrefix(uri, "http") {
+			uri = joinURL(host, uri)
+		}
+		res = append(res, SiteMapImage{URL: uri})
+	}
+	add(a.HeaderImageURL)
+	for _, im := range a.Images {
+		add(im.relativeURL)
+	}
+	return res
+}
+
+// buildSiteMapURLs returns all urls that should be in the sitemap
+func buildSiteMapURLs(store *Articles, host string) []SiteMapURL {
 	articles := store.getNotHidden()
-	urlset := makeSiteMapURLSet()
 	var urls []SiteMapURL
+	var lastModified time.Time
 	for _, article := range articles {
-		pageURL := path.Join(host, article.URL())
+		if article.UpdatedOn.After(lastModified) {
+			lastModified = article.UpdatedOn
+		}
 		uri := SiteMapURL{
-			URL:          pageURL,
-			LastModified: article.UpdatedOn.Format("2006-01-02"),
+			URL:          joinURL(host, article.URL()),
+			LastModified: formatSiteMapDate(article.UpdatedOn),
+			Images:       articleSiteMapImages(host, article),
 		}
 		urls = append(urls, uri)
 	}
+	if lastModified.IsZero() {
+		lastModified = time.Now()
+	}
 
-	now := time.Now()
-	for _, staticURL := range staticURLS {
-		pageURL := path.Join(host, staticURL)
+	// pages that list articles change when articles change
+	var listURLS []string
+	for _, lp := range buildListPages(store, blogTags(store)) {
+		listURLS = append(listURLS, lp.URL)
+	}
+	for _, s := range store.series {
+		listURLS = append(listURLS, s.URL())
+	}
+	for _, c := range store.collections {
+		listURLS = append(listURLS, c.URL)
+	}
+	listURLS = append(listURLS, staticURLS...)
+	for _, pageURL := range listURLS {
+		if pageURL == "/index.html" {
+			pageURL = "/"
+		}
 		uri := SiteMapURL{
-			URL:          pageURL,
-			LastModified: now.Format("2006-01-02"),
+			URL:          joinURL(host, pageURL),
+			LastModified: formatSiteMapDate(lastModified),
 		}
 		urls = append(urls, uri)
 	}
+	return urls
+}
 
-	urlset.URLS = urls
+func siteMapPartURL(n int) string {
+	return fmt.Sprintf("/sitemap-%d.xml", n)
+}
+
+// numSiteMapParts returns 0 if all urls fit in /sitemap.xml or number
+// of /sitemap-${n}.xml files listed in sitemap index
+func numSiteMapParts(nURLS int) int {
+	if nURLS <= siteMapMaxURLs {
+		return 0
+	}
+	return (nURLS + siteMapMaxURLs - 1) / siteMapMaxURLs
+}
 
-	xmlData, err := xml.MarshalIndent(urlset, "", "")
+func marshalSiteMap(v interface{}) ([]byte, error) {
+	xmlData, err := xml.MarshalIndent(v, "", "")
 	if err != nil {
 		return nil, err
 	}
 	d := append([]byte(xml.Header), xmlData...)
 	return d, nil
 }
+
+func genSiteMapURLSet(urls []SiteMapURL) ([]byte, error) {
+	urlset := makeSiteMapURLSet()
+	urlset.URLS = urls
+	return marshalSiteMap(urlset)
+}
+
+// genSiteMap generates /sitemap.xml, which is a sitemap index if there
+// are too many urls for a single sitemap
+func genSiteMap(store *Articles, host string) ([]byte, error) {
+	urls := buildSiteMapURLs(store, host)
+	n := numSiteMapParts(len(urls))
+	if n == 0 {
+		return genSiteMapURLSet(urls)
+	}
+	idx := &SiteMapIndex{
+		Ns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+	}
+	now := formatSiteMapDate(time.Now())
+	for i := 1; i <= n; i++ {
+		ref := SiteMapRef{
+			URL:          joinURL(host, siteMapPartURL(i)),
+			LastModified: now,
+		}
+		idx.SiteMaps = append(idx.SiteMaps, ref)
+	}
+	return marshalSiteMap(idx)
+}
+
+// genSiteMapPart generates /sitemap-${n}.xml (n is 1-based)
+func genSiteMapPart(store *Articles, host string, n int) ([]byte, error) {
+	urls := buildSiteMapURLs(store, host)
+	start := (n - 1) * siteMapMaxURLs
+	if n < 1 || start >= len(urls) {
+		return nil, fmt.Errorf("genSiteMapPart: invalid part %d", n)
+	}
+	end := start + siteMapMaxURLs
+	if end > len(urls) {
+		end = len(urls)
+	}
+	return genSiteMapURLSet(urls[start:end])
+}
+
+// genRobotsTxt generates /robots.txt
+func genRobotsTxt(host string) []byte {
+	var sb strings.Builder
+	sb.WriteString("User-agent: *\n")
+	for _, uri := range robotsDisallow {
+		sb.WriteString("Disallow: " + uri + "\n")
+	}
+	sb.WriteString("\nSitemap: " + joinURL(host, "/sitemap.xml") + "\n")
+	return []byte(sb.String())
+}
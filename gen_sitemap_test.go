@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestGenSiteMap(t *testing.T) {
+	assert.Equal(t, "https://example.com/foo.html", joinURL("https://example.com/", "/foo.html"))
+	assert.Equal(t, "https://example.com/foo.html", joinURL("https://example.com", "foo.html"))
+
+	assert.Equal(t, 0, numSiteMapParts(siteMapMaxURLs))
+	assert.Equal(t, 2, numSiteMapParts(siteMapMaxURLs+1))
+
+	store := makeFeedTestStore()
+	store.articles[0].Images = []*ImageMapping{
+		{relativeURL: "/img/cover.png"},
+		{relativeURL: "/img/diagram.png"},
+	}
+	host := "https://ntheanh201.vercel.app"
+	d, err := genSiteMap(store, host)
+	assert.NoError(t, err)
+	s := string(d)
+	assert.True(t, strings.Contains(s, "<loc>https://ntheanh201.vercel.app/articles/xin-chao.html</loc>"))
+	assert.True(t, strings.Contains(s, "<image:image><image:loc>https://ntheanh201.vercel.app/img/diagram.png</image:loc></image:image>"))
+	assert.Equal(t, 1, strings.Count(s, "/img/cover.png"))
+	assert.True(t, strings.Contains(s, "<loc>https://ntheanh201.vercel.app/</loc>"))
+	assert.True(t, strings.Contains(s, "<loc>https://ntheanh201.vercel.app/archives.html</loc>"))
+	assert.True(t, strings.Contains(s, "<loc>https://ntheanh201.vercel.app/tag/go.html</loc>"))
+	assert.False(t, strings.Contains(s, "https:/ntheanh201"))
+
+	_, err = genSiteMapPart(store, host, 2)
+	assert.Error(t, err)
+
+	robots := string(genRobotsTxt(host))
+	assert.True(t, strings.Contains(robots, "Disallow: /notes/\n"))
+	assert.False(t, strings.Contains(robots, "/preview/"))
+	assert.True(t, strings.HasSuffix(robots, "Sitemap: https://ntheanh201.vercel.app/sitemap.xml\n"))
+}
@@ -10,6 +10,5 @@ require (
 	github.com/kjk/minioutil v0.0.0-20220709072721-3afa88d5f27b
 	github.com/kjk/notionapi v0.0.0-20220710025316-0b93466e11f8
 	github.com/microcosm-cc/bluemonday v1.0.19
-	github.com/mitchellh/mapstructure v1.5.0
-	github.com/thomas11/atomgenerator v0.0.0-20140514140532-0b3b01da14a4
+	golang.org/x/text v0.3.7
 )
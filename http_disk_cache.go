@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/kjk/common/httputil"
+)
+
+// errNotCached is returned by HTTPDiskCache in cache-only mode
+var errNotCached = errors.New("not cached")
+
+type httpDiskCacheEntry struct {
+	URL          string    `json:"url"`
+	DownloadedOn time.Time `json:"downloadedOn"`
+	Data         string    `json:"data"`
+}
+
+// HTTPDiskCache is like HTTPDownloadCache but stores downloads on disk,
+// forever, so that they can be checked in next to Notion cache
+type HTTPDiskCache struct {
+	Dir string
+	// if true, we don't download, only return what's cached
+	CacheOnly bool
+}
+
+// NewHTTPDiskCache creates HTTPDiskCache
+func NewHTTPDiskCache(dir string, cacheOnly bool) *HTTPDiskCache {
+	return &HTTPDiskCache{
+		Dir:       dir,
+		CacheOnly: cacheOnly,
+	}
+}
+
+func (c *HTTPDiskCache) pathForURL(uri string) string {
+	return filepath.Join(c.Dir, sha1HexOf([]byte(uri))+".json")
+}
+
+// Download returns content of uri, from cache if possible
+func (c *HTTPDiskCache) Download(uri string) ([]byte, bool, error) {
+	path := c.pathForURL(uri)
+	if d, err := ioutil.ReadFile(path); err == nil {
+		var e httpDiskCacheEntry
+		if err = json.Unmarshal(d, &e); err == nil && e.URL == uri {
+			return []byte(e.Data), true, nil
+		}
+		logerrf(ctx(), "HTTPDiskCache: bad cache file '%s' for '%s'\n", path, uri)
+	}
+	if c.CacheOnly {
+		return nil, false, errNotCached
+	}
+
+	d, err := httputil.Get(uri)
+	if err != nil {
+		return nil, false, err
+	}
+	e := &httpDiskCacheEntry{
+		URL:          uri,
+		DownloadedOn: time.Now().UTC(),
+		Data:         string(d),
+	}
+	js, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return nil, false, err
+	}
+	if err = createDirForFile(path); err != nil {
+		return nil, false, err
+	}
+	if err = ioutil.WriteFile(path, js, 0644); err != nil {
+		return nil, false, err
+	}
+	return d, false, nil
+}
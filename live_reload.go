@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kjk/notionapi"
+)
+
+// in -run-dev we watch Notion cache, templates and css for changes,
+// reload affected articles and tell the browser to refresh the page
+
+const (
+	liveReloadURL          = "/dev/livereload"
+	liveReloadPollInterval = 500 * time.Millisecond
+)
+
+// injected into html served by -run-dev
+const liveReloadScript = `<script>
+(function () {
+    var es = new EventSource("` + liveReloadURL + `");
+    es.onmessage = function (e) {
+        if (e.data === "reload") {
+            window.location.reload();
+        }
+    };
+})();
+</script>
+`
+
+var (
+	liveReloadEnabled bool
+
+	liveReloadMu      sync.Mutex
+	liveReloadClients = map[chan string]bool{}
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshotFiles returns stamps of files in dirs. If recursive is false
+// we don't look into sub-directories
+func snapshotFiles(dirs []string, recursive bool) map[string]fileStamp {
+	res := map[string]fileStamp{}
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if e.IsDir() {
+				if path != dir && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			fi, err := e.Info()
+			if err != nil {
+				return nil
+			}
+			res[path] = fileStamp{fi.ModTime(), fi.Size()}
+			return nil
+		})
+	}
+	return res
+}
+
+// diffSnapshots returns paths of files that were added, changed or removed
+func diffSnapshots(prev, curr map[string]fileStamp) []string {
+	var res []string
+	for path, st := range curr {
+		if prevSt, ok := prev[path]; !ok || prevSt != st {
+			res = append(res, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := curr[path]; !ok {
+			res = append(res, path)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// watchFiles polls dirs and calls onChange with paths of changed files.
+// Polling is good enough for a dev server and works everywhere
+func watchFiles(dirs []string, recursive bool, onChange func(paths []string)) {
+	prev := snapshotFiles(dirs, recursive)
+	for {
+		time.Sleep(liveReloadPollInterval)
+		curr := snapshotFiles(dirs, recursive)
+		if changed := diffSnapshots(prev, curr); len(changed) > 0 {
+			onChange(changed)
+		}
+		prev = curr
+	}
+}
+
+// pageIDsFromCachePaths returns ids of Notion pages whose cache files
+// (notion_cache/${id}.txt) changed
+func pageIDsFromCachePaths(paths []string) []string {
+	var res []string
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if filepath.Ext(name) != ".txt" {
+			continue
+		}
+		id := strings.TrimSuffix(name, ".txt")
+		if !notionapi.IsValidNoDashID(id) {
+			continue
+		}
+		res = append(res, id)
+	}
+	return res
+}
+
+// reloadArticles re-reads changed pages from Notion cache and rebuilds
+// all articles, because changes in one page (e.g. title) can affect
+// html of other pages
+func reloadArticles(pageIDs []string) {
+	timeStart := time.Now()
+	// don't take the server down because of a bad page
+	defer func() {
+		if p := recover(); p != nil {
+			logerrf(ctx(), "reloadArticles: panicked with '%v'\n", p)
+		}
+	}()
+	cc := getNotionCachingClient()
+
+	articlesMu.RLock()
+	idToPage := map[string]*notionapi.Page{}
+	for id, page := range allArticles.idToPage {
+		idToPage[id] = page
+	}
+	articlesMu.RUnlock()
+
+	for _, id := range pageIDs {
+		path := filepath.Join(cacheDir, id+".txt")
+		if !fileExists(path) {
+			delete(idToPage, id)
+			continue
+		}
+		page, err := cc.DownloadPage(id)
+		if err != nil {
+			logerrf(ctx(), "reloadArticles: cc.DownloadPage('%s') failed with '%s'\n", id, err)
+			continue
+		}
+		idToPage[id] = page
+	}
+
+	// building articles also updates redirects, so we block serving
+	// until it's done
+	articlesMu.Lock()
+	defer articlesMu.Unlock()
+	resetArticleRedirects()
+	store := buildArticles(cc, idToPage)
+	setAllArticles(store)
+	logf(ctx(), "reloadArticles: reloaded %d pages, %d articles in %s\n", len(pageIDs), len(store.articles), time.Since(timeStart))
+}
+
+func notifyLiveReload() {
+	liveReloadMu.Lock()
+	defer liveReloadMu.Unlock()
+	for ch := range liveReloadClients {
+		// don't block if the client didn't read previous notification
+		select {
+		case ch <- "reload":
+		default:
+		}
+	}
+}
+
+// startLiveReload starts watching for changes in Notion cache, templates
+// and css
+func startLiveReload() {
+	liveReloadEnabled = true
+
+	go watchFiles([]string{cacheDir}, false, func(paths []string) {
+		ids := pageIDsFromCachePaths(paths)
+		if len(ids) == 0 {
+			return
+		}
+		logf(ctx(), "live reload: %d pages changed in '%s'\n", len(ids), cacheDir)
+		reloadArticles(ids)
+		notifyLiveReload()
+	})
+
+	wwwDirs := []string{filepath.Join("www", "tmpl"), filepath.Join("www", "css")}
+	go watchFiles(wwwDirs, true, func(paths []string) {
+		logf(ctx(), "live reload: changed %s\n", strings.Join(paths, ", "))
+		// templates are re-loaded and css is read from disk on every request
+		notifyLiveReload()
+	})
+}
+
+// handleLiveReload streams Server-Sent Events that tell the browser
+// to reload the page
+// /dev/livereload
+func handleLiveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan string, 1)
+	liveReloadMu.Lock()
+	liveReloadClients[ch] = true
+	liveReloadMu.Unlock()
+	defer func() {
+		liveReloadMu.Lock()
+		delete(liveReloadClients, ch)
+		liveReloadMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// http.Server has WriteTimeout so we end the stream before that.
+	// EventSource re-connects automatically
+	timeout := time.After(60 * time.Second)
+	for {
+		select {
+		case msg := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", msg)
+			flusher.Flush()
+		case <-timeout:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// injectLiveReloadScript adds liveReloadScript to html page
+func injectLiveReloadScript(d []byte) []byte {
+	s := string(d)
+	idx := strings.LastIndex(s, "</body>")
+	if idx == -1 {
+		return d
+	}
+	return []byte(s[:idx] + liveReloadScript + s[idx:])
+}
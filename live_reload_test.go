@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	t1 := time.Now()
+	t2 := t1.Add(time.Second)
+	prev := map[string]fileStamp{
+		"a.txt": {t1, 10},
+		"b.txt": {t1, 10},
+		"c.txt": {t1, 10},
+	}
+	curr := map[string]fileStamp{
+		"a.txt": {t1, 10},
+		"b.txt": {t2, 10},
+		"d.txt": {t1, 5},
+	}
+	assert.Equal(t, []string{"b.txt", "c.txt", "d.txt"}, diffSnapshots(prev, curr))
+	assert.Equal(t, 0, len(diffSnapshots(curr, curr)))
+
+	paths := []string{"notion_cache/0367c2db381a4f8b9ce360f388a6b2e3.txt", "notion_cache/files/foo.png", "notion_cache/foo.txt"}
+	assert.Equal(t, []string{"0367c2db381a4f8b9ce360f388a6b2e3"}, pageIDsFromCachePaths(paths))
+}
+
+func TestInjectLiveReloadScript(t *testing.T) {
+	got := string(injectLiveReloadScript([]byte("<html><body>hi</body></html>")))
+	assert.Equal(t, "<html><body>hi"+liveReloadScript+"</body></html>", got)
+	got = string(injectLiveReloadScript([]byte("<?xml?>")))
+	assert.Equal(t, "<?xml?>", got)
+}
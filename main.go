@@ -20,6 +20,10 @@ var (
 
 	flgVerbose bool
 	flgNoCache bool
+	// if true, articles with PublishedOn in the future are shown
+	flgPreviewFuture bool
+	// if true, unpublished articles are available under /preview/
+	flgPreviewDrafts bool
 
 	cacheDir      = "notion_cache"
 	cachingPolicy = notionapi.PolicyDownloadNewer
@@ -44,10 +48,14 @@ func main() {
 		flgRunProd         bool
 		flgImportNotion    bool
 		flgGen             bool
+		flgIncremental     bool
+		flgListScheduled   bool
 		flgDiff            bool
 		flgCiDaily         bool
 		flgImportNotionOne string
 		flgProfile         string
+		flgHost            string
+		flgDir             string
 	)
 
 	{
@@ -58,9 +66,16 @@ func main() {
 		flag.BoolVar(&flgRunProd, "run-prod", false, "run server in production")
 		flag.BoolVar(&flgImportNotion, "import-notion", false, "re-download the content from Notion. use -no-cache to disable cache")
 		flag.BoolVar(&flgGen, "gen", false, "gen html in www_generated/ directory")
+		flag.BoolVar(&flgIncremental, "incremental", false, "with -gen, only re-write files that changed since last -gen")
+		flag.BoolVar(&flgPreviewFuture, "preview-future", false, "with -run-dev, show articles scheduled to be published in the future")
+		flag.BoolVar(&flgPreviewDrafts, "preview-drafts", false, "with -gen or -run-dev, make unpublished articles available under secret /preview/ urls")
+		flag.BoolVar(&flgListScheduled, "list-scheduled", false, "list articles scheduled to be published in the future")
 		//flag.BoolVar(&flgDiff, "diff", false, "preview diff using winmerge")
 		flag.BoolVar(&flgCiDaily, "ci-update-from-notion", false, "incrementally update from notion")
 		//flag.StringVar(&flgProfile, "profile", "", "name of file to save cpu profiling info")
+		flag.StringVar(&configPath, "config", configPath, "path of site config file")
+		flag.StringVar(&flgHost, "host", "", "over-rides hostURL from config file e.g. https://staging.example.com")
+		flag.StringVar(&flgDir, "dir", "", "over-rides dir from config file")
 		flag.Parse()
 	}
 
@@ -69,7 +84,19 @@ func main() {
 		logf(ctx(), "finished in %s\n", time.Since(timeStart))
 	}()
 
-	cdUpDir("blog")
+	{
+		var err error
+		siteConfig, err = loadSiteConfig(findConfigFile(configPath))
+		must(err)
+		if flgHost != "" {
+			siteConfig.HostURL = strings.TrimSuffix(flgHost, "/")
+		}
+		if flgDir != "" {
+			siteConfig.Dir = flgDir
+		}
+	}
+
+	cdUpDir(siteConfig.Dir)
 
 	if false {
 		dirToLF(".")
@@ -82,14 +109,25 @@ func main() {
 	}
 
 	if false {
-		flgImportNotionOne = "68f077a6dfb346358f219875e80ea72c"
+		flgImportNotionOne = siteConfig.Notion.WebsiteStartPage
 	}
 
 	// for those commands we only want to use cache
-	if flgGen || flgRunDev {
+	if flgGen || flgRunDev || flgListScheduled {
 		cachingPolicy = notionapi.PolicyCacheOnly
 	}
 
+	// scheduled articles must never leak into generated website
+	if !flgRunDev {
+		flgPreviewFuture = false
+	}
+
+	if flgListScheduled {
+		cc := getNotionCachingClient()
+		listScheduledArticles(loadArticles(cc), os.Stdout)
+		return
+	}
+
 	if flgRunDev {
 		runServerDev()
 		return
@@ -155,7 +193,7 @@ func main() {
 				runCmdLoggedMust(cmd)
 			*/
 
-			cmd = exec.Command("git", "add", "notion_cache")
+			cmd = exec.Command("git", "add", "notion_cache", urlHistoryPath)
 			runCmdLoggedMust(cmd)
 			nowStr := time.Now().Format("2006-01-02")
 			commitMsg := "ci: update from notion on " + nowStr
@@ -192,7 +230,7 @@ func main() {
 
 	if flgGen {
 		cachingPolicy = notionapi.PolicyCacheOnly
-		genHTMLServer(dirWwwGenerated)
+		genHTMLServer(dirWwwGenerated, flgIncremental)
 		return
 	}
 
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kjk/notionapi"
+)
+
+// Audio and video files uploaded to Notion are downloaded into
+// notion_cache/files, like images, and served under /media/.
+// YouTube and Vimeo videos are embedded with privacy-enhanced players
+// that are only loaded after clicking on the poster. Posters are
+// downloaded when importing and served from /img/
+
+// over-written in tests
+var oembedVimeoURL = "https://vimeo.com/api/oembed.json"
+
+var (
+	// bigger files are not downloaded and are rendered as a link because
+	// notion_cache is committed to git and Cloudflare Pages doesn't serve
+	// files over 25 MiB. Over-written in tests
+	mediaMaxSize = 25 * 1024 * 1024
+
+	// remembers files that were too big so that we don't download
+	// them on every import
+	mediaTooBigPath = filepath.Join(cacheDir, "media_too_big.json")
+
+	// link => size of files bigger than mediaMaxSize, loaded on first use
+	mediaTooBig map[string]int
+)
+
+var mediaExts = map[string]string{
+	".aac":  "audio",
+	".flac": "audio",
+	".m4a":  "audio",
+	".mp3":  "audio",
+	".oga":  "audio",
+	".ogg":  "audio",
+	".opus": "audio",
+	".wav":  "audio",
+	".m4v":  "video",
+	".mov":  "video",
+	".mp4":  "video",
+	".ogv":  "video",
+	".webm": "video",
+}
+
+var (
+	rxYouTubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	rxVimeoID   = regexp.MustCompile(`^[0-9]+$`)
+	rxVimeoHash = regexp.MustCompile(`^[0-9a-f]+$`)
+	rxTimeParts = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`)
+)
+
+// isMediaFile returns true if path is an audio or video file
+func isMediaFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return mediaExts[ext] != ""
+}
+
+func readMediaTooBig() map[string]int {
+	res := map[string]int{}
+	d, err := ioutil.ReadFile(mediaTooBigPath)
+	if err != nil {
+		return res
+	}
+	if err = json.Unmarshal(d, &res); err != nil {
+		logerrf(ctx(), "readMediaTooBig: json.Unmarshal of '%s' failed with '%s'\n", mediaTooBigPath, err)
+		return map[string]int{}
+	}
+	return res
+}
+
+func rememberMediaTooBig(link string, size int) {
+	mediaTooBig[link] = size
+	d, err := json.MarshalIndent(mediaTooBig, "", "  ")
+	must(err)
+	must(createDirForFile(mediaTooBigPath))
+	must(ioutil.WriteFile(mediaTooBigPath, d, 0644))
+}
+
+// downloadMedia downloads audio or video file uploaded to Notion.
+// Unlike images, failing to download is not fatal: the block is
+// rendered as a link to the source
+func (a *Article) downloadMedia(block *notionapi.Block) {
+	link := block.Source
+	if mediaTooBig == nil {
+		mediaTooBig = readMediaTooBig()
+	}
+	if size, ok := mediaTooBig[link]; ok {
+		logvf("downloadMedia: '%s' from page https://notion.so/%s is too big (%d bytes)\n", link, normalizeID(a.page.ID), size)
+		return
+	}
+	resp, err := a.notionClient.DownloadFile(link, block)
+	if err != nil {
+		logf(ctx(), "downloadMedia: DownloadFile('%s') from page https://notion.so/%s failed with '%s'\n", link, normalizeID(a.page.ID), err)
+		return
+	}
+	if !resp.FromCache {
+		logf(ctx(), "downloadMedia: DownloadFile('%s') from page https://notion.so/%s\n", link, normalizeID(a.page.ID))
+	}
+	path := resp.CacheFilePath
+	if size := len(resp.Data); size > mediaMaxSize {
+		logf(ctx(), "downloadMedia: '%s' from page https://notion.so/%s is %d bytes, more than %d, will link to it\n", link, normalizeID(a.page.ID), size, mediaMaxSize)
+		must(os.Remove(path))
+		rememberMediaTooBig(link, size)
+		return
+	}
+	if !isMediaFile(path) {
+		logf(ctx(), "downloadMedia: '%s' from page https://notion.so/%s is not an audio or video file\n", path, normalizeID(a.page.ID))
+		return
+	}
+	im := &ImageMapping{
+		link:        link,
+		path:        path,
+		relativeURL: "/media/" + filepath.Base(path),
+	}
+	a.Media = append(a.Media, im)
+}
+
+// downloadVideoPoster downloads thumbnail of YouTube or Vimeo video so
+// that we don't load it from their servers on every page view
+func (a *Article) downloadVideoPoster(block *notionapi.Block) {
+	uri := block.Source
+	posterURL := ""
+	if id, _ := youTubeVideo(uri); id != "" {
+		posterURL = "https://i.ytimg.com/vi/" + id + "/hqdefault.jpg"
+	} else if id, _ := vimeoVideo(uri); id != "" {
+		res, err := fetchOEmbed(newEmbedsCache(), oembedVimeoURL, uri, nil)
+		if err != nil {
+			logf(ctx(), "downloadVideoPoster: getting oEmbed for '%s' failed with '%s'\n", uri, err)
+			return
+		}
+		posterURL = res.ThumbnailURL
+	}
+	if posterURL == "" {
+		return
+	}
+	resp, err := a.notionClient.DownloadFile(posterURL, block)
+	if err != nil {
+		logf(ctx(), "downloadVideoPoster: DownloadFile('%s') for '%s' failed with '%s'\n", posterURL, uri, err)
+		return
+	}
+	im := &ImageMapping{
+		link:        uri,
+		path:        resp.CacheFilePath,
+		relativeURL: "/img/" + filepath.Base(resp.CacheFilePath),
+	}
+	a.videoPosters = append(a.videoPosters, im)
+}
+
+func findMediaMapping(media []*ImageMapping, link string) *ImageMapping {
+	for _, im := range media {
+		if im.link == link {
+			return im
+		}
+	}
+	return nil
+}
+
+// parseStartTime parses YouTube's t= parameter i.e. "90", "90s" or "1m30s"
+func parseStartTime(s string) int {
+	m := rxTimeParts.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	secs := 0
+	for i, mult := range []int{3600, 60, 1} {
+		n, _ := strconv.Atoi(m[i+1])
+		secs += n * mult
+	}
+	return secs
+}
+
+// youTubeVideo returns id and start time in seconds of YouTube video
+// from watch, youtu.be, embed and shorts urls
+func youTubeVideo(uri string) (string, int) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", 0
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	q := u.Query()
+	id := ""
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		switch parts[0] {
+		case "watch":
+			id = q.Get("v")
+		case "embed", "shorts", "live", "v":
+			if len(parts) > 1 {
+				id = parts[1]
+			}
+		}
+	}
+	if !rxYouTubeID.MatchString(id) {
+		return "", 0
+	}
+	start := parseStartTime(q.Get("t"))
+	if start == 0 {
+		start = parseStartTime(q.Get("start"))
+	}
+	return id, start
+}
+
+// vimeoVideo returns id and, for unlisted videos, hash of Vimeo video
+func vimeoVideo(uri string) (string, string) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	if host != "vimeo.com" && host != "player.vimeo.com" {
+		return "", ""
+	}
+	id, hash := "", ""
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, part := range parts {
+		if !rxVimeoID.MatchString(part) {
+			continue
+		}
+		id = part
+		// https://vimeo.com/${id}/${hash} for unlisted videos
+		if i+1 < len(parts) && rxVimeoHash.MatchString(parts[i+1]) {
+			hash = parts[i+1]
+		}
+		break
+	}
+	if h := u.Query().Get("h"); h != "" && rxVimeoHash.MatchString(h) {
+		hash = h
+	}
+	if id == "" {
+		return "", ""
+	}
+	return id, hash
+}
+
+// videoPosterDoc is shown in the iframe until the user clicks play,
+// so that nothing is loaded from YouTube or Vimeo before that.
+// posterURL is a local url of downloaded poster or ""
+func videoPosterDoc(playerURL string, posterURL string, title string) string {
+	s := `<style>*{padding:0;margin:0;overflow:hidden}html,body{height:100%;background:#000}img,span{position:absolute;width:100%;top:0;bottom:0;margin:auto}img{height:100%;object-fit:cover}span{height:1.5em;text-align:center;font:48px/1.5 sans-serif;color:#fff;text-shadow:0 0 .5em #000}</style>`
+	s += fmt.Sprintf(`<a href="%s">`, html.EscapeString(playerURL))
+	if posterURL != "" {
+		s += fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(posterURL), html.EscapeString(title))
+	}
+	s += `<span>&#x25B6;</span></a>`
+	return s
+}
+
+func videoIframeHTML(playerURL string, posterURL string, title string) string {
+	doc := videoPosterDoc(playerURL, posterURL, title)
+	return fmt.Sprintf(`<div class="video-embed"><iframe src="%s" srcdoc="%s" title="%s" loading="lazy" allow="autoplay; encrypted-media; picture-in-picture; fullscreen" allowfullscreen></iframe></div>`, html.EscapeString(playerURL), html.EscapeString(doc), html.EscapeString(title))
+}
+
+// youTubeEmbedHTML uses youtube-nocookie.com player
+func youTubeEmbedHTML(uri string, posterURL string) (string, bool) {
+	id, start := youTubeVideo(uri)
+	if id == "" {
+		return "", false
+	}
+	playerURL := "https://www.youtube-nocookie.com/embed/" + id + "?autoplay=1"
+	if start > 0 {
+		playerURL += "&start=" + strconv.Itoa(start)
+	}
+	return videoIframeHTML(playerURL, posterURL, "YouTube video"), true
+}
+
+// vimeoEmbedHTML uses Vimeo player with do-not-track. Title comes from
+// oEmbed response, if it's cached
+func vimeoEmbedHTML(cache *HTTPDiskCache, uri string, posterURL string) (string, bool) {
+	id, hash := vimeoVideo(uri)
+	if id == "" {
+		return "", false
+	}
+	playerURL := "https://player.vimeo.com/video/" + id + "?dnt=1&autoplay=1"
+	if hash != "" {
+		playerURL += "&h=" + hash
+	}
+	title := "Vimeo video"
+	res, err := fetchOEmbed(cache, oembedVimeoURL, uri, nil)
+	if err == nil && res.Title != "" {
+		title = res.Title
+	} else if err != nil && err != errNotCached {
+		logf(ctx(), "vimeoEmbedHTML: getting oEmbed for '%s' failed with '%s'\n", uri, err)
+	}
+	return videoIframeHTML(playerURL, posterURL, title), true
+}
+
+// RenderMedia renders BlockAudio and BlockVideo. Returns false, i.e.
+// renders a link, for files we didn't download and unknown video sites
+func (c *Converter) RenderMedia(block *notionapi.Block) bool {
+	uri := block.Source
+	var s, kind string
+	if im := findMediaMapping(c.article.Media, uri); im != nil {
+		src := html.EscapeString(im.relativeURL)
+		kind = mediaExts[strings.ToLower(filepath.Ext(im.path))]
+		// audio block with a video file only plays the sound
+		if block.Type == notionapi.BlockAudio {
+			kind = "audio"
+		}
+		s = fmt.Sprintf(`<%s controls preload="metadata" src="%s"`, kind, src)
+		if kind == "video" {
+			s += ` playsinline`
+		}
+		s += fmt.Sprintf(`><a href="%s">Download %s</a></%s>`, src, kind, kind)
+	} else if block.Type == notionapi.BlockVideo {
+		posterURL := ""
+		if im := findMediaMapping(c.article.videoPosters, uri); im != nil {
+			posterURL = im.relativeURL
+		}
+		var ok bool
+		kind = "youtube"
+		s, ok = youTubeEmbedHTML(uri, posterURL)
+		if !ok {
+			kind = "vimeo"
+			s, ok = vimeoEmbedHTML(c.embeds, uri, posterURL)
+		}
+		if !ok {
+			return false
+		}
+	} else {
+		return false
+	}
+	c.r.Printf(`<figure id="%s" class="media media-%s">`, block.ID, kind)
+	c.r.Printf(`%s`, s)
+	c.r.RenderCaption(block)
+	c.r.Printf(`</figure>`)
+	return true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+)
+
+func TestIsMediaFile(t *testing.T) {
+	assert.True(t, isMediaFile("notion_cache/files/abc.mp4"))
+	assert.True(t, isMediaFile("abc.MP3"))
+	assert.False(t, isMediaFile("abc.png"))
+	assert.False(t, isMediaFile("abc"))
+}
+
+func TestYouTubeVideo(t *testing.T) {
+	tests := []struct {
+		uri   string
+		id    string
+		start int
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", 0},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ&t=90", "dQw4w9WgXcQ", 90},
+		{"https://youtu.be/dQw4w9WgXcQ?t=1m30s", "dQw4w9WgXcQ", 90},
+		{"https://www.youtube.com/embed/dQw4w9WgXcQ?start=5", "dQw4w9WgXcQ", 5},
+		{"https://youtube.com/shorts/dQw4w9WgXcQ", "dQw4w9WgXcQ", 0},
+		{"https://www.youtube.com/channel/UC123", "", 0},
+		{"https://www.youtube.com/watch?v=short", "", 0},
+		{"https://vimeo.com/76979871", "", 0},
+	}
+	for _, test := range tests {
+		id, start := youTubeVideo(test.uri)
+		assert.Equal(t, test.id, id)
+		assert.Equal(t, test.start, start)
+	}
+}
+
+func TestVimeoVideo(t *testing.T) {
+	tests := []struct {
+		uri  string
+		id   string
+		hash string
+	}{
+		{"https://vimeo.com/76979871", "76979871", ""},
+		{"https://vimeo.com/channels/staffpicks/76979871", "76979871", ""},
+		{"https://vimeo.com/76979871/a1b2c3d4e5", "76979871", "a1b2c3d4e5"},
+		{"https://player.vimeo.com/video/76979871?h=a1b2c3d4e5", "76979871", "a1b2c3d4e5"},
+		{"https://vimeo.com/staffpicks", "", ""},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "", ""},
+	}
+	for _, test := range tests {
+		id, hash := vimeoVideo(test.uri)
+		assert.Equal(t, test.id, id)
+		assert.Equal(t, test.hash, hash)
+	}
+}
+
+func TestYouTubeEmbed(t *testing.T) {
+	s, ok := youTubeEmbedHTML("https://youtu.be/dQw4w9WgXcQ?t=42", "/img/poster.jpg")
+	assert.True(t, ok)
+	assert.True(t, strings.Contains(s, `src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?autoplay=1&amp;start=42"`))
+	assert.True(t, strings.Contains(s, `loading="lazy"`))
+	// poster is shown in srcdoc until the video is played
+	assert.True(t, strings.Contains(s, `srcdoc="&lt;style&gt;`))
+	assert.True(t, strings.Contains(s, `/img/poster.jpg`))
+	assert.False(t, strings.Contains(s, `ytimg`))
+
+	// without downloaded poster there's only the play button
+	s, ok = youTubeEmbedHTML("https://youtu.be/dQw4w9WgXcQ", "")
+	assert.True(t, ok)
+	assert.False(t, strings.Contains(s, `&lt;img`))
+
+	_, ok = youTubeEmbedHTML("https://example.com/video.mp4", "")
+	assert.False(t, ok)
+}
+
+func TestVimeoEmbed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vimeo/oembed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"video","title":"Big Buck Bunny","thumbnail_url":"https://i.vimeocdn.com/video/1.jpg"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	prev := oembedVimeoURL
+	oembedVimeoURL = srv.URL + "/vimeo/oembed"
+	defer func() { oembedVimeoURL = prev }()
+
+	cache := NewHTTPDiskCache(t.TempDir(), false)
+	s, ok := vimeoEmbedHTML(cache, "https://vimeo.com/76979871", "/img/poster.jpg")
+	assert.True(t, ok)
+	assert.True(t, strings.Contains(s, `src="https://player.vimeo.com/video/76979871?dnt=1&amp;autoplay=1"`))
+	assert.True(t, strings.Contains(s, `title="Big Buck Bunny"`))
+	assert.True(t, strings.Contains(s, `/img/poster.jpg`))
+	// poster is never hot-linked
+	assert.False(t, strings.Contains(s, `vimeocdn`))
+
+	// without cached oEmbed data we use generic title
+	cache = NewHTTPDiskCache(t.TempDir(), true)
+	s, ok = vimeoEmbedHTML(cache, "https://vimeo.com/76979871", "")
+	assert.True(t, ok)
+	assert.True(t, strings.Contains(s, `title="Vimeo video"`))
+}
+
+// writeCachedFile puts a file in cache of CachingClient, as if it was
+// downloaded from uri
+func writeCachedFile(t *testing.T, dir string, uri string, ext string, size int) string {
+	path := filepath.Join(dir, "files", sha1HexOf([]byte(uri))+ext)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	return path
+}
+
+func TestDownloadMedia(t *testing.T) {
+	dir := t.TempDir()
+	cc, err := notionapi.NewCachingClient(dir, &notionapi.Client{})
+	assert.NoError(t, err)
+	cc.Policy = notionapi.PolicyCacheOnly
+
+	prevMaxSize, prevPath := mediaMaxSize, mediaTooBigPath
+	mediaMaxSize = 50
+	mediaTooBigPath = filepath.Join(dir, "media_too_big.json")
+	mediaTooBig = nil
+	defer func() {
+		mediaMaxSize, mediaTooBigPath, mediaTooBig = prevMaxSize, prevPath, nil
+	}()
+
+	small := "https://s3-us-west-2.amazonaws.com/secure.notion-static.com/1/small.mp4"
+	big := "https://s3-us-west-2.amazonaws.com/secure.notion-static.com/2/big.mp4"
+	youTube := "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	smallPath := writeCachedFile(t, dir, small, ".mp4", 10)
+	bigPath := writeCachedFile(t, dir, big, ".mp4", 100)
+	posterPath := writeCachedFile(t, dir, "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg", ".jpg", 10)
+
+	a := &Article{page: &notionapi.Page{ID: pageID1}, notionClient: cc}
+	a.downloadMedia(&notionapi.Block{Type: notionapi.BlockVideo, Source: small})
+	assert.Len(t, a.Media, 1)
+	assert.Equal(t, "/media/"+filepath.Base(smallPath), a.Media[0].relativeURL)
+
+	// too big files are rendered as a link and not downloaded again
+	a.downloadMedia(&notionapi.Block{Type: notionapi.BlockVideo, Source: big})
+	assert.Len(t, a.Media, 1)
+	assert.False(t, fileExists(bigPath))
+	assert.Equal(t, 100, readMediaTooBig()[big])
+
+	a.downloadVideoPoster(&notionapi.Block{Type: notionapi.BlockVideo, Source: youTube})
+	assert.Len(t, a.videoPosters, 1)
+	assert.Equal(t, youTube, a.videoPosters[0].link)
+	assert.Equal(t, "/img/"+filepath.Base(posterPath), a.videoPosters[0].relativeURL)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kjk/notionapi"
+	"github.com/ntheanh201/blog/entity"
+)
+
+var (
+	// collection id => schema, remembered from collection queries
+	collectionSchemas = map[string]map[string]*notionapi.ColumnSchema{}
+)
+
+func rememberCollectionSchemas(rm *notionapi.RecordMap) {
+	if rm == nil {
+		return
+	}
+	for id, r := range rm.Collections {
+		col := r.Collection
+		if col == nil {
+			if len(r.Value) == 0 {
+				continue
+			}
+			col = &notionapi.Collection{}
+			if err := json.Unmarshal(r.Value, col); err != nil {
+				logf(ctx(), "rememberCollectionSchemas: failed to decode collection '%s': '%s'\n", id, err)
+				continue
+			}
+		}
+		if len(col.Schema) == 0 {
+			continue
+		}
+		collectionSchemas[notionapi.ToNoDashID(id)] = col.Schema
+	}
+}
+
+// downloadCollectionSchema downloads the collection record, for pages
+// that were not reached through a collection query
+func downloadCollectionSchema(client *notionapi.Client, collectionID string) (map[string]*notionapi.ColumnSchema, error) {
+	// same as unexported notionapi.syncRecordRequest
+	req := struct {
+		Requests []notionapi.PointerWithVersion `json:"requests"`
+	}{
+		Requests: []notionapi.PointerWithVersion{
+			{
+				Pointer: notionapi.Pointer{ID: notionapi.ToDashID(collectionID), Table: notionapi.TableCollection},
+				Version: -1,
+			},
+		},
+	}
+	rsp, err := client.SyncRecordValues(req)
+	if err != nil {
+		return nil, err
+	}
+	rememberCollectionSchemas(rsp.RecordMap)
+	schema := collectionSchemas[notionapi.ToNoDashID(collectionID)]
+	if schema == nil {
+		return nil, fmt.Errorf("no schema in collection record")
+	}
+	return schema, nil
+}
+
+// returns schema of a database the page belongs to or nil if the page
+// is not a database row. The schema comes from the page, a collection
+// query or, as a last resort, is downloaded
+func collectionSchemaForPage(c *notionapi.CachingClient, page *notionapi.Page) (map[string]*notionapi.ColumnSchema, error) {
+	root := page.Root()
+	if root.ParentTable != notionapi.TableCollection {
+		return nil, nil
+	}
+	if col := page.CollectionByID(notionapi.NewNotionID(root.ParentID)); col != nil && len(col.Schema) > 0 {
+		return col.Schema, nil
+	}
+	collectionID := notionapi.ToNoDashID(root.ParentID)
+	if schema := collectionSchemas[collectionID]; schema != nil {
+		return schema, nil
+	}
+	if c == nil || c.Policy == notionapi.PolicyCacheOnly {
+		return nil, fmt.Errorf("page %s: schema of database %s is not known, re-import from Notion", normalizeID(page.ID), collectionID)
+	}
+	logf(ctx(), "collectionSchemaForPage: downloading database %s of page %s\n", collectionID, normalizeID(page.ID))
+	schema, err := downloadCollectionSchema(c.Client, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("page %s: downloading schema of database %s failed with '%w'", normalizeID(page.ID), collectionID, err)
+	}
+	return schema, nil
+}
+
+// resolveNotionProperties maps logical field name to property id
+// by looking up column names in the schema
+func resolveNotionProperties(schema map[string]*notionapi.ColumnSchema, props *NotionProperties) (map[string]string, error) {
+	nameToID := map[string]string{}
+	var names []string
+	for id, col := range schema {
+		nameToID[strings.ToLower(col.Name)] = id
+		names = append(names, col.Name)
+	}
+	sort.Strings(names)
+
+	fields := []struct {
+		field  string
+		column string
+	}{
+		{"Title", props.Title},
+		{"Slug", props.Slug},
+		{"Tags", props.Tags},
+		{"Type", props.Type},
+		{"Status", props.Status},
+		{"PublishedOn", props.PublishedOn},
+		{"Description", props.Description},
+		{"Cover", props.Cover},
+		{"Series", props.Series},
+		{"SeriesOrder", props.SeriesOrder},
+	}
+	res := map[string]string{}
+	for _, f := range fields {
+		if f.column == "" {
+			continue
+		}
+		id, ok := nameToID[strings.ToLower(f.column)]
+		if !ok {
+			return nil, fmt.Errorf("column '%s' mapped to %s doesn't exist in Notion database. Available columns: %s", f.column, f.field, strings.Join(names, ", "))
+		}
+		res[f.field] = id
+	}
+	return res, nil
+}
+
+func getPropertyText(block *notionapi.Block, id string) string {
+	spans := block.GetProperty(id)
+	return strings.TrimSpace(getInlineBlocksText(spans))
+}
+
+// date columns are stored as "‣" text with a date attribute
+func getPropertyDate(block *notionapi.Block, id string) string {
+	for _, span := range block.GetProperty(id) {
+		for _, attr := range span.Attrs {
+			if notionapi.AttrGetType(attr) == notionapi.AttrDate {
+				return notionapi.AttrGetDate(attr).StartDate
+			}
+		}
+	}
+	return ""
+}
+
+// file and url columns have the url in the link attribute
+func getPropertyURL(block *notionapi.Block, id string) string {
+	for _, span := range block.GetProperty(id) {
+		for _, attr := range span.Attrs {
+			if notionapi.AttrGetType(attr) == notionapi.AttrLink {
+				return notionapi.AttrGetLink(attr)
+			}
+		}
+		if strings.HasPrefix(span.Text, "http") {
+			return span.Text
+		}
+	}
+	return ""
+}
+
+// notionPageProperties reads database columns of a page according
+// to siteConfig.Notion.Properties. Pages outside of a database have
+// no properties
+func notionPageProperties(c *notionapi.CachingClient, page *notionapi.Page) (*entity.BlockExtend, error) {
+	res := &entity.BlockExtend{}
+	schema, err := collectionSchemaForPage(c, page)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return res, nil
+	}
+	ids, err := resolveNotionProperties(schema, &siteConfig.Notion.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("page %s: %w", normalizeID(page.ID), err)
+	}
+	root := page.Root()
+	for field, id := range ids {
+		switch field {
+		case "Title":
+			res.Title = getPropertyText(root, id)
+		case "Slug":
+			res.Slug = getPropertyText(root, id)
+		case "Tags":
+			res.Tags = parseTags(getPropertyText(root, id))
+		case "Type":
+			res.Type = getPropertyText(root, id)
+		case "Status":
+			res.Status = getPropertyText(root, id)
+		case "PublishedOn":
+			res.PublishedOn = getPropertyDate(root, id)
+		case "Description":
+			res.Description = getPropertyText(root, id)
+		case "Cover":
+			res.Cover = getPropertyURL(root, id)
+		case "Series":
+			res.Series = getPropertyText(root, id)
+		case "SeriesOrder":
+			res.SeriesOrder = getPropertyText(root, id)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+)
+
+func TestResolveNotionProperties(t *testing.T) {
+	schema := map[string]*notionapi.ColumnSchema{
+		"title": {Name: "Name"},
+		"d]hq":  {Name: "Slug"},
+		"sD^m":  {Name: "Tags"},
+	}
+	props := &NotionProperties{
+		Title: "Name",
+		Slug:  "slug",
+		Tags:  "Tags",
+	}
+	ids, err := resolveNotionProperties(schema, props)
+	assert.NoError(t, err)
+	assert.Equal(t, "title", ids["Title"])
+	assert.Equal(t, "d]hq", ids["Slug"])
+	assert.Equal(t, "sD^m", ids["Tags"])
+	assert.Equal(t, 3, len(ids))
+
+	props.Status = "Status"
+	_, err = resolveNotionProperties(schema, props)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "'Status' mapped to Status"))
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestDownloadCollectionSchema(t *testing.T) {
+	collectionID := "0f2b9c1e-6a8d-4a3e-9b1d-2c3e4f5a6b7c"
+	var reqBody string
+	t.Cleanup(func() {
+		delete(collectionSchemas, notionapi.ToNoDashID(collectionID))
+	})
+	client := &notionapi.Client{
+		HTTPClient: &http.Client{
+			Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				d, _ := ioutil.ReadAll(r.Body)
+				reqBody = string(d)
+				rsp := `{"recordMap":{"collection":{"` + collectionID + `":{"role":"reader","value":{"id":"` + collectionID + `","schema":{"title":{"name":"Name","type":"title"},"d]hq":{"name":"Slug","type":"text"}}}}}}}`
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(strings.NewReader(rsp)),
+				}, nil
+			}),
+		},
+	}
+	schema, err := downloadCollectionSchema(client, notionapi.ToNoDashID(collectionID))
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(reqBody, `"table": "collection"`))
+	assert.Equal(t, "Slug", schema["d]hq"].Name)
+	assert.NotNil(t, collectionSchemas[notionapi.ToNoDashID(collectionID)])
+}
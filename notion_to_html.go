@@ -17,6 +17,8 @@ type Converter struct {
 	notionClient *notionapi.CachingClient
 	idToArticle  func(string) *Article
 	galleries    [][]string
+	// data for tweets, gists etc.
+	embeds *HTTPDiskCache
 
 	r *tohtml.Converter
 }
@@ -25,7 +27,15 @@ type Converter struct {
 // =>
 // /articles/${id}
 func (c *Converter) rewriteURL(uri string) string {
-	id := notionapi.ExtractNoDashIDFromNotionURL(uri)
+	// link to a block in this page
+	if strings.HasPrefix(uri, "#") {
+		return "#" + blockAnchor(c.article, uri[1:])
+	}
+	pageURL, blockID := uri, ""
+	if idx := strings.LastIndex(uri, "#"); idx != -1 {
+		pageURL, blockID = uri[:idx], uri[idx+1:]
+	}
+	id := notionapi.ExtractNoDashIDFromNotionURL(pageURL)
 	if id == "" {
 		return uri
 	}
@@ -34,9 +44,22 @@ func (c *Converter) rewriteURL(uri string) string {
 	if article == nil {
 		return uri
 	}
+	// link to a block in another page
+	if blockID != "" {
+		return article.URL() + "#" + blockAnchor(article, blockID)
+	}
 	return article.URL()
 }
 
+// blockAnchor returns readable anchor of a header block or block id
+// for other blocks
+func blockAnchor(article *Article, blockID string) string {
+	if anchor := article.headerAnchors[notionapi.ToNoDashID(blockID)]; anchor != "" {
+		return anchor
+	}
+	return blockID
+}
+
 func (c *Converter) getURLAndTitleForBlock(block *notionapi.Block) (string, string) {
 	id := notionapi.ToNoDashID(block.ID)
 	article := c.idToArticle(id)
@@ -190,6 +213,10 @@ func (c *Converter) blockRenderOverride(block *notionapi.Block) bool {
 		return c.RenderCode(block)
 	case notionapi.BlockImage:
 		return c.RenderImage(block)
+	case notionapi.BlockAudio, notionapi.BlockVideo:
+		return c.RenderMedia(block)
+	case notionapi.BlockTweet, notionapi.BlockGist, notionapi.BlockCodepen, notionapi.BlockFigma, notionapi.BlockMaps:
+		return c.RenderEmbed(block)
 	}
 	return false
 }
@@ -200,12 +227,16 @@ func NewHTMLConverter(c *notionapi.CachingClient, article *Article) *Converter {
 		notionClient: c,
 		article:      article,
 		page:         article.page,
+		embeds:       newEmbedsCache(),
 	}
 
 	r := tohtml.NewConverter(article.page)
 	notionapi.PanicOnFailures = true
 	r.RenderBlockOverride = res.blockRenderOverride
 	r.RewriteURL = res.rewriteURL
+	r.AddHeaderAnchor = true
+	r.ReadableHeaderIDs = true
+	r.RenderMathML = true
 	res.r = r
 
 	return res
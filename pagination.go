@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pager describes position of a page in a paginated list of articles
+type Pager struct {
+	Page   int
+	NPages int
+	// empty if there is no previous / next page
+	PrevURL string
+	NextURL string
+}
+
+// listPage is a single page of index, archive or tag archive
+type listPage struct {
+	URL string
+	// true for index, false for archives
+	isIndex bool
+	// for tag archives
+	tag  string
+	page int
+}
+
+// pageURL returns url of n-th page of a list whose first page is firstURL
+// e.g. "/tag/go.html" => "/tag/go/page/2.html", "/index.html" => "/page/2.html"
+func pageURL(firstURL string, n int) string {
+	if n <= 1 {
+		return firstURL
+	}
+	base := strings.TrimSuffix(firstURL, ".html")
+	if firstURL == "/index.html" {
+		base = ""
+	}
+	return fmt.Sprintf("%s/page/%d.html", base, n)
+}
+
+func tagURL(tag string) string {
+	return "/tag/" + tag + ".html" // TODO: URL-escape?
+}
+
+func archiveURL(tag string) string {
+	if tag == "" {
+		return "/archives.html"
+	}
+	return tagURL(tag)
+}
+
+func numPages(nItems int, pageSize int) int {
+	if pageSize <= 0 || nItems <= pageSize {
+		return 1
+	}
+	return (nItems + pageSize - 1) / pageSize
+}
+
+// paginate returns articles for a given page (1-based)
+func paginate(articles []*Article, page int, pageSize int) []*Article {
+	if pageSize <= 0 {
+		return articles
+	}
+	start := (page - 1) * pageSize
+	if start >= len(articles) {
+		return nil
+	}
+	end := start + pageSize
+	if end > len(articles) {
+		end = len(articles)
+	}
+	return articles[start:end]
+}
+
+func makePager(firstURL string, page int, nPages int) *Pager {
+	p := &Pager{
+		Page:   page,
+		NPages: nPages,
+	}
+	if page > 1 {
+		p.PrevURL = pageURL(firstURL, page-1)
+	}
+	if page < nPages {
+		p.NextURL = pageURL(firstURL, page+1)
+	}
+	return p
+}
+
+// splitPosts splits articles into posts and (static) pages
+func splitPosts(articles []*Article) ([]*Article, []*Article) {
+	var posts, pages []*Article
+	for _, a := range articles {
+		if a.Type == "Page" {
+			pages = append(pages, a)
+		} else {
+			posts = append(posts, a)
+		}
+	}
+	return posts, pages
+}
+
+func sortNewestFirst(articles []*Article) {
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].PublishedOn.After(articles[j].PublishedOn)
+	})
+}
+
+// archiveArticles returns posts shown in the archive for the tag
+// (all posts if tag is ""), newest first
+func archiveArticles(store *Articles, tag string) []*Article {
+	posts, _ := splitPosts(store.articles)
+	if tag != "" {
+		posts = filterArticlesByTag(posts, tag, true)
+	}
+	sortNewestFirst(posts)
+	return posts
+}
+
+// blogTags returns sorted tags of blog articles
+func blogTags(store *Articles) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, article := range store.getBlogNotHidden() {
+		for _, tag := range article.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				res = append(res, tag)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// buildListPages returns all pages of the index, archives and tag archives
+func buildListPages(store *Articles, tags []string) []*listPage {
+	var res []*listPage
+	posts, _ := splitPosts(store.articles)
+	n := numPages(len(posts), siteConfig.Pagination.IndexPageSize)
+	for i := 1; i <= n; i++ {
+		res = append(res, &listPage{URL: pageURL("/index.html", i), isIndex: true, page: i})
+	}
+	tags = append([]string{""}, tags...)
+	for _, tag := range tags {
+		nPosts := len(archiveArticles(store, tag))
+		n = numPages(nPosts, siteConfig.Pagination.ArchivePageSize)
+		for i := 1; i <= n; i++ {
+			res = append(res, &listPage{URL: pageURL(archiveURL(tag), i), tag: tag, page: i})
+		}
+	}
+	return res
+}
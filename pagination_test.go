@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestPageURL(t *testing.T) {
+	assert.Equal(t, "/index.html", pageURL("/index.html", 1))
+	assert.Equal(t, "/page/2.html", pageURL("/index.html", 2))
+	assert.Equal(t, "/archives/page/3.html", pageURL("/archives.html", 3))
+	assert.Equal(t, "/tag/go/page/2.html", pageURL(tagURL("go"), 2))
+
+	assert.Equal(t, 1, numPages(0, 10))
+	assert.Equal(t, 1, numPages(25, 0))
+	assert.Equal(t, 3, numPages(21, 10))
+
+	p := makePager("/index.html", 2, 3)
+	assert.Equal(t, "/index.html", p.PrevURL)
+	assert.Equal(t, "/page/3.html", p.NextURL)
+}
+
+func TestPaginatedIndex(t *testing.T) {
+	prev := siteConfig
+	defer func() {
+		siteConfig = prev
+	}()
+	cfg := *siteConfig
+	cfg.Pagination = PaginationConfig{IndexPageSize: 2, ArchivePageSize: 3}
+	siteConfig = &cfg
+
+	t1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &Articles{}
+	for i := 0; i < 5; i++ {
+		a := &Article{
+			ID:          fmt.Sprintf("a%d", i),
+			Title:       fmt.Sprintf("Article %d", i),
+			PublishedOn: t1.Add(time.Duration(i) * time.Hour),
+			inBlog:      true,
+		}
+		if i%2 == 0 {
+			a.Tags = []string{"go"}
+		}
+		store.articles = append(store.articles, a)
+		store.blog = append(store.blog, a)
+	}
+	store.articles = append(store.articles, &Article{ID: "p", Title: "About", Type: "Page"})
+
+	var urls []string
+	for _, lp := range buildListPages(store, blogTags(store)) {
+		urls = append(urls, lp.URL)
+	}
+	exp := []string{"/index.html", "/page/2.html", "/page/3.html", "/archives.html", "/archives/page/2.html", "/tag/go.html"}
+	assert.Equal(t, exp, urls)
+
+	var buf bytes.Buffer
+	assert.NoError(t, genIndex(store, 2, &buf))
+	s := buf.String()
+	assert.True(t, strings.Contains(s, `<link rel="prev" href="/index.html">`))
+	assert.True(t, strings.Contains(s, `<link rel="next" href="/page/3.html">`))
+	assert.True(t, strings.Contains(s, "Article 2"))
+	assert.False(t, strings.Contains(s, "Article 4"))
+	assert.False(t, strings.Contains(s, "About"))
+}
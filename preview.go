@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+)
+
+// unpublished articles can be shared for review under
+// /preview/${token}/${slug}.html. Tokens are random so that urls
+// can't be guessed and are remembered so that urls don't change
+// between runs.
+// Tokens are secret so they must not be committed: notion_cache is
+// pushed to the public repo by -ci-update-from-notion and
+// www_generated is tracked, so both tokens and generated preview pages
+// are in .gitignore
+
+// file that maps page id to preview token, in .gitignore
+const previewTokensFileName = "preview_tokens.json"
+
+func previewTokensPath() string {
+	return previewTokensFileName
+}
+
+func readPreviewTokens(path string) map[string]string {
+	res := map[string]string{}
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return res
+	}
+	if err = json.Unmarshal(d, &res); err != nil {
+		logerrf(ctx(), "readPreviewTokens: json.Unmarshal of '%s' failed with '%s'\n", path, err)
+		return map[string]string{}
+	}
+	return res
+}
+
+func writePreviewTokens(path string, tokens map[string]string) {
+	d, err := json.MarshalIndent(tokens, "", "  ")
+	must(err)
+	must(createDirForFile(path))
+	must(ioutil.WriteFile(path, d, 0644))
+}
+
+func genPreviewToken() string {
+	var d [16]byte
+	_, err := rand.Read(d[:])
+	must(err)
+	return hex.EncodeToString(d[:])
+}
+
+// assignPreviewTokens gives articles a preview token, re-using tokens
+// stored in path
+func assignPreviewTokens(articles []*Article, path string) {
+	tokens := readPreviewTokens(path)
+	changed := false
+	for _, a := range articles {
+		token := tokens[a.ID]
+		if token == "" {
+			token = genPreviewToken()
+			tokens[a.ID] = token
+			changed = true
+		}
+		a.previewToken = token
+	}
+	if changed {
+		writePreviewTokens(path, tokens)
+	}
+}
+
+// buildPreviews returns hidden articles that get a preview url
+func buildPreviews(hidden []*Article) []*Article {
+	res := append([]*Article{}, hidden...)
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	assignPreviewTokens(res, previewTokensPath())
+	for _, a := range res {
+		logf(ctx(), "preview: %s%s %s\n", getHostURL(), a.PreviewURL(), a.Title)
+	}
+	return res
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestAssignPreviewTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), previewTokensFileName)
+	a1 := &Article{ID: "a1", Title: "Bản nháp"}
+	a2 := &Article{ID: "a2", Title: "Draft"}
+	assignPreviewTokens([]*Article{a1}, path)
+	assert.Equal(t, 32, len(a1.previewToken))
+	assert.True(t, a1.IsPreview())
+	assert.Equal(t, "/preview/"+a1.previewToken+"/ban-nhap.html", a1.PreviewURL())
+
+	// tokens are stable across runs and unique per article
+	b1 := &Article{ID: "a1", Title: "Bản nháp"}
+	assignPreviewTokens([]*Article{b1, a2}, path)
+	assert.Equal(t, a1.previewToken, b1.previewToken)
+	assert.NotEqual(t, a1.previewToken, a2.previewToken)
+	assert.Equal(t, 2, len(readPreviewTokens(path)))
+}
+
+// preview urls are secret so they must not end up in the repo
+func TestPreviewFilesNotCommitted(t *testing.T) {
+	if err := exec.Command("git", "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		t.Skip("not in a git repository")
+	}
+	a := &Article{ID: "a1", Title: "Draft", previewToken: genPreviewToken()}
+	paths := []string{
+		previewTokensPath(),
+		filepath.Join(dirWwwGenerated, filepath.FromSlash(a.PreviewURL())),
+	}
+	for _, path := range paths {
+		err := exec.Command("git", "check-ignore", "-q", path).Run()
+		assert.NoError(t, err, "'%s' is not in .gitignore", path)
+	}
+}
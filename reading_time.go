@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"regexp"
+	"time"
+	"unicode"
+)
+
+const (
+	// words per minute for languages that separate words with spaces
+	// (English, Vietnamese)
+	readingWordsPerMinute = 230
+	// characters per minute for Chinese and Japanese
+	readingCJKCharsPerMinute = 500
+)
+
+var rxCodeBlock = regexp.MustCompile(`(?is)<pre[\s>].*?</pre>`)
+
+// isCJKChar returns true for characters of languages that don't separate
+// words with spaces so we count each character
+func isCJKChar(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r)
+}
+
+// countWords returns number of space-separated words and CJK characters
+// in text. Vietnamese syllables are counted as words
+func countWords(text string) (words int, cjkChars int) {
+	inWord := false
+	runes := []rune(text)
+	for i, r := range runes {
+		if isCJKChar(r) {
+			cjkChars++
+			inWord = false
+			continue
+		}
+		// unicode.Mn are combining marks e.g. Vietnamese diacritics
+		// when not pre-composed
+		isWordChar := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		// apostrophe between letters is part of a word e.g. "it's", "don’t"
+		if (r == '\'' || r == '’') && inWord && i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+			isWordChar = true
+		}
+		if isWordChar && !inWord {
+			words++
+		}
+		inWord = isWordChar
+	}
+	return words, cjkChars
+}
+
+// readingTime returns estimated reading time, in minutes. At least 1
+func readingTime(words int, cjkChars int) int {
+	minutes := float64(words)/readingWordsPerMinute + float64(cjkChars)/readingCJKCharsPerMinute
+	res := int(minutes + 0.5)
+	if res < 1 {
+		return 1
+	}
+	return res
+}
+
+// setReadingStats sets WordCount, CodeWordCount and ReadingTime based
+// on html of the article. Code blocks are counted separately and don't
+// affect reading time
+func setReadingStats(a *Article) {
+	var codeWords int
+	for _, code := range rxCodeBlock.FindAllString(a.BodyHTML, -1) {
+		n, _ := countWords(htmlToText(code))
+		codeWords += n
+	}
+	s := rxCodeBlock.ReplaceAllString(a.BodyHTML, " ")
+	words, cjkChars := countWords(htmlToText(s))
+	a.WordCount = words + cjkChars
+	a.CodeWordCount = codeWords
+	a.ReadingTime = readingTime(words, cjkChars)
+}
+
+// ReadingTimeDisplay returns reading time as text e.g. "5 min read"
+func (a *Article) ReadingTimeDisplay() string {
+	return fmt.Sprintf("%d min read", a.ReadingTime)
+}
+
+// articleStructuredData returns schema.org BlogPosting as JSON-LD
+func articleStructuredData(a *Article, canonicalURL string) template.JS {
+	v := map[string]interface{}{
+		"@context":      "https://schema.org",
+		"@type":         "BlogPosting",
+		"headline":      a.Title,
+		"url":           canonicalURL,
+		"datePublished": a.PublishedOn.Format(time.RFC3339),
+		"wordCount":     a.WordCount,
+		// ISO 8601 duration
+		"timeRequired": fmt.Sprintf("PT%dM", a.ReadingTime),
+		"author": map[string]string{
+			"@type": "Person",
+			"name":  siteConfig.Author.Name,
+			"url":   siteConfig.Author.URL,
+		},
+	}
+	if !a.UpdatedOn.IsZero() {
+		v["dateModified"] = a.UpdatedOn.Format(time.RFC3339)
+	}
+	if a.Description != "" {
+		v["description"] = a.Description
+	}
+	if len(a.Tags) > 0 {
+		v["keywords"] = a.Tags
+	}
+	if a.HeaderImageURL != "" {
+		v["image"] = getHostURL() + a.HeaderImageURL
+	}
+	d, err := json.Marshal(v)
+	must(err)
+	return template.JS(d)
+}
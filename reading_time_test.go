@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestCountWords(t *testing.T) {
+	words, cjk := countWords("Hello, world! It's 2022.")
+	assert.Equal(t, 4, words)
+	assert.Equal(t, 0, cjk)
+
+	words, _ = countWords("Don’t stop 'quoted' rock 'n' roll")
+	assert.Equal(t, 6, words)
+
+	// Vietnamese syllables are separated by spaces
+	words, cjk = countWords("Xin chào thế giới")
+	assert.Equal(t, 4, words)
+	assert.Equal(t, 0, cjk)
+
+	// not pre-composed i.e. with combining marks
+	words, _ = countWords("thế giới")
+	assert.Equal(t, 2, words)
+
+	words, cjk = countWords("你好世界 and こんにちは")
+	assert.Equal(t, 1, words)
+	assert.Equal(t, 9, cjk)
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, 1, readingTime(0, 0))
+	assert.Equal(t, 1, readingTime(100, 0))
+	assert.Equal(t, 5, readingTime(1150, 0))
+	assert.Equal(t, 2, readingTime(0, 1000))
+}
+
+func TestSetReadingStats(t *testing.T) {
+	body := strings.Repeat("word ", 460)
+	a := &Article{
+		BodyHTML: "<p>" + body + "</p><pre class=\"code\"><code>func main() {}</code></pre><p>Use <code>go build</code></p>",
+	}
+	setReadingStats(a)
+	assert.Equal(t, 463, a.WordCount)
+	assert.Equal(t, 2, a.CodeWordCount)
+	assert.Equal(t, 2, a.ReadingTime)
+	assert.Equal(t, "2 min read", a.ReadingTimeDisplay())
+}
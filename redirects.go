@@ -1,133 +1,338 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-var articleRedirectsTxt = `
-`
+// All redirects are described by Redirect. Manual redirects are in
+// redirects.json, more are added when loading articles (e.g. from old
+// article urls). The Go server enforces them and -gen exports them as
+// _redirects (Netlify and Cloudflare Pages) and vercel.json
 
-var redirects = [][]string{
-	{"/index.html", "/"},
-	{"/blog", "/"},
-	{"/blog/", "/"},
-	{"/feed/rss2/atom.xml", "/atom.xml"},
-	{"/feed/rss2/", "/atom.xml"},
-	{"/feed/rss2", "/atom.xml"},
-	{"/feed/", "/atom.xml"},
-	{"/feed", "/atom.xml"},
-	{"/feedburner.xml", "/atom.xml"},
+// file with manual redirects
+const redirectsPath = "redirects.json"
+
+// Redirect describes a redirect or a rewrite (if Code is 200).
+// From can end with "*", which matches any suffix. The matched suffix
+// is available in To as ":splat". A segment of From like ":id" matches
+// a single path segment, available in To as ":id".
+// Like Netlify and unlike Vercel, a redirect only applies if there's
+// no file for From (except wildcard redirects in vercel.json)
+type Redirect struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// 301, 302, 307, 308 or 200 for rewrite
+	Code int `json:"code"`
+
+	// From compiled by compile(), nil if From is not a pattern
+	rx *regexp.Regexp
+	// names of rx groups e.g. ":id" or ":splat"
+	rxNames []string
+}
+
+func newRedirect(from, to string, code int) *Redirect {
+	r := &Redirect{
+		From: from,
+		To:   to,
+		Code: code,
+	}
+	r.compile()
+	return r
 }
 
-var articleRedirects = make(map[string]string)
+type redirectsFile struct {
+	Redirects []*Redirect `json:"redirects"`
+}
 
-func readRedirects(store *Articles) {
-	d := []byte(articleRedirectsTxt)
-	lines := bytes.Split(d, []byte{'\n'})
-	for _, l := range lines {
-		if len(l) == 0 {
-			continue
+var (
+	// old article urls were /articles/${id}/${title}
+	builtinRedirects = []*Redirect{
+		newRedirect("/articles/:id/*", "/articles/:id.html", http.StatusOK),
+	}
+	// from redirects.json
+	fileRedirects []*Redirect
+	// added when loading articles
+	articleRedirects []*Redirect
+
+	rxRedirectParam = regexp.MustCompile(`:[A-Za-z]+`)
+)
+
+func isValidRedirectCode(code int) bool {
+	switch code {
+	case 200, 301, 302, 307, 308:
+		return true
+	}
+	return false
+}
+
+func isExternalURL(uri string) bool {
+	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")
+}
+
+func parseRedirectsJSON(d []byte) ([]*Redirect, error) {
+	var f redirectsFile
+	if err := json.Unmarshal(d, &f); err != nil {
+		return nil, err
+	}
+	for _, r := range f.Redirects {
+		if r.Code == 0 {
+			r.Code = http.StatusFound
 		}
-		parts := strings.Split(string(l), "|")
-		panicIf(len(parts) != 2, "malformed article_redirects.txt, len(parts) = %d (!2)", len(parts))
-		idStr := parts[0]
-		url := strings.TrimSpace(parts[1])
-		idNum, err := strconv.Atoi(idStr)
-		panicIf(err != nil, "malformed line in article_redirects.txt. Line:\n%s\nError: %s\n", l, err)
-		id := encodeBase64(idNum)
-		a := store.idToArticle[id]
-		if a != nil {
-			articleRedirects[url] = id
-			continue
+		if !strings.HasPrefix(r.From, "/") || r.To == "" {
+			return nil, fmt.Errorf("invalid redirect '%s' => '%s'", r.From, r.To)
 		}
-		//logvf("skipping redirect '%s' because article with id %d no longer present\n", string(l), id)
+		if !isValidRedirectCode(r.Code) {
+			return nil, fmt.Errorf("redirect '%s' has invalid code %d", r.From, r.Code)
+		}
+		r.compile()
 	}
+	return f.Redirects, nil
 }
 
-var (
-	wwwRedirects []*wwwRedirect
-)
+func readRedirectsFile(path string) ([]*Redirect, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := parseRedirectsJSON(d)
+	if err != nil {
+		return nil, fmt.Errorf("readRedirectsFile: '%s': %w", path, err)
+	}
+	return res, nil
+}
 
-type wwwRedirect struct {
-	from string
-	to   string
-	// valid code is 301, 302, 200, 404
-	code int
+func loadFileRedirectsMust() {
+	var err error
+	fileRedirects, err = readRedirectsFile(redirectsPath)
+	must(err)
 }
 
+// addRedirect adds a redirect, replacing previous redirect for the same url
 func addRedirect(from, to string, code int) {
-	r := wwwRedirect{
-		from: from,
-		to:   to,
-		code: code,
+	for _, r := range articleRedirects {
+		if r.From == from {
+			r.To = to
+			r.Code = code
+			return
+		}
+	}
+	articleRedirects = append(articleRedirects, newRedirect(from, to, code))
+}
+
+// resetArticleRedirects removes redirects added when loading articles so
+// that re-loading them doesn't keep redirects of renamed or removed articles
+func resetArticleRedirects() {
+	articleRedirects = nil
+}
+
+// getAllRedirects returns redirects in the order they should be matched.
+// Manual redirects come first so that they can over-ride generated ones
+func getAllRedirects() []*Redirect {
+	res := append([]*Redirect{}, fileRedirects...)
+	res = append(res, builtinRedirects...)
+	sorted := append([]*Redirect{}, articleRedirects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].From < sorted[j].From
+	})
+	return append(res, sorted...)
+}
+
+// compile converts From like "/articles/:id/*" to regexp
+// `^/articles/([^/]+)/(.*)$` so that match() doesn't have to.
+// Must be called after changing From
+func (r *Redirect) compile() {
+	r.rx = nil
+	r.rxNames = nil
+	if !strings.Contains(r.From, "*") && !rxRedirectParam.MatchString(r.From) {
+		return
+	}
+	var names []string
+	rxs := regexp.QuoteMeta(strings.TrimSuffix(r.From, "*"))
+	rxs = rxRedirectParam.ReplaceAllStringFunc(rxs, func(s string) string {
+		names = append(names, s)
+		return `([^/]+)`
+	})
+	if strings.HasSuffix(r.From, "*") {
+		names = append(names, ":splat")
+		rxs += `(.*)`
 	}
-	wwwRedirects = append(wwwRedirects, &r)
+	r.rx = regexp.MustCompile("^" + rxs + "$")
+	r.rxNames = names
 }
 
-func addRewrite(from, to string) {
-	addRedirect(from, to, 200)
+// isPattern returns true if From has "*" or ":name" segments
+func (r *Redirect) isPattern() bool {
+	return r.rx != nil
 }
 
-func addTempRedirect(from, to string) {
-	addRedirect(from, to, 302)
+// match returns destination for uri or "" if r doesn't match uri
+func (r *Redirect) match(uri string) string {
+	if !r.isPattern() {
+		if uri == r.From {
+			return r.To
+		}
+		return ""
+	}
+	m := r.rx.FindStringSubmatch(uri)
+	if m == nil {
+		return ""
+	}
+	vals := map[string]string{}
+	for i, name := range r.rxNames {
+		vals[name] = m[i+1]
+	}
+	return rxRedirectParam.ReplaceAllStringFunc(r.To, func(s string) string {
+		if v, ok := vals[s]; ok {
+			return v
+		}
+		return s
+	})
 }
 
-func addStaticRedirects() {
-	for _, redirect := range redirects {
-		from := redirect[0]
-		to := redirect[1]
-		addTempRedirect(from, to)
+// findRedirect returns the first redirect that matches uri and its destination
+func findRedirect(redirects []*Redirect, uri string) (*Redirect, string) {
+	for _, r := range redirects {
+		if to := r.match(uri); to != "" {
+			return r, to
+		}
 	}
+	return nil, ""
 }
 
-func addArticleRedirects(store *Articles) {
-	readRedirects(store)
-	for from, articleID := range articleRedirects {
-		from = "/" + from
-		article := store.idToArticle[articleID]
-		panicIf(article == nil, "didn't find article for id '%s'", articleID)
-		to := article.URL()
-		addTempRedirect(from, to) // TODO: change to permanent
+// validateRedirects checks that redirects don't loop and that internal
+// destinations exist
+func validateRedirects(redirects []*Redirect, urlExists func(string) bool) []error {
+	var errs []error
+	seen := map[string]*Redirect{}
+	for _, r := range redirects {
+		if prev := seen[r.From]; prev != nil && prev.To != r.To {
+			errs = append(errs, fmt.Errorf("'%s' redirects to both '%s' and '%s'", r.From, prev.To, r.To))
+			continue
+		}
+		seen[r.From] = r
 	}
 
+	for _, r := range redirects {
+		if r.isPattern() {
+			// can't validate without knowing the urls
+			continue
+		}
+		// follow the chain of internal redirects
+		visited := map[string]bool{r.From: true}
+		to := r.To
+		for {
+			if isExternalURL(to) {
+				break
+			}
+			if visited[to] {
+				errs = append(errs, fmt.Errorf("redirect loop: '%s' => '%s'", r.From, to))
+				break
+			}
+			visited[to] = true
+			next, nextTo := findRedirect(redirects, to)
+			if next == nil || next.isPattern() {
+				if !urlExists(to) {
+					errs = append(errs, fmt.Errorf("'%s' redirects to '%s' which doesn't exist", r.From, to))
+				}
+				break
+			}
+			to = nextTo
+		}
+	}
+	return errs
 }
 
-// redirect /articles/:id/* => /articles/:id/pretty-title
-const wwwRedirectsProlog = `/articles/:id/*	/articles/:id.html	200
-`
+// genRedirectsTxt generates _redirects used by Netlify and Cloudflare Pages
+func genRedirectsTxt(redirects []*Redirect) []byte {
+	var buf bytes.Buffer
+	for _, r := range redirects {
+		fmt.Fprintf(&buf, "%s %s %d\n", r.From, r.To, r.Code)
+	}
+	return buf.Bytes()
+}
 
-func writeRedirects() {
-	buf := bytes.NewBufferString(wwwRedirectsProlog)
-	for _, r := range wwwRedirects {
-		s := fmt.Sprintf("%s\t%s\t%d\n", r.from, r.to, r.code)
-		buf.WriteString(s)
+// parseRedirectsTxt parses _redirects generated by genRedirectsTxt
+func parseRedirectsTxt(d []byte) ([]*Redirect, error) {
+	var res []*Redirect
+	scanner := bufio.NewScanner(bytes.NewReader(d))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Fields(line)
+		r := &Redirect{
+			Code: http.StatusMovedPermanently,
+		}
+		switch len(parts) {
+		case 3:
+			code, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid code in line '%s'", line)
+			}
+			r.Code = code
+			fallthrough
+		case 2:
+			r.From = parts[0]
+			r.To = parts[1]
+		default:
+			return nil, fmt.Errorf("invalid line '%s'", line)
+		}
+		r.compile()
+		res = append(res, r)
 	}
-	wwwWriteFile("_redirects", buf.Bytes())
+	return res, scanner.Err()
 }
 
-type redirectInfo struct {
-	URL  string
-	Code int
+type vercelRoute struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	StatusCode  int    `json:"statusCode,omitempty"`
 }
 
-func readRedirectsJSON() map[string]redirectInfo {
-	res := map[string]redirectInfo{}
-	d := readFileMust("redirects.json")
-	var js map[string]interface{}
-	must(json.Unmarshal(d, &js))
-	for k, v := range js {
-		a := v.([]interface{})
-		uri := a[0].(string)
-		code := (int)(a[1].(float64))
-		ri := redirectInfo{
-			URL:  uri,
-			Code: code,
+type vercelConfig struct {
+	CleanURLs bool           `json:"cleanUrls"`
+	Redirects []*vercelRoute `json:"redirects,omitempty"`
+	Rewrites  []*vercelRoute `json:"rewrites,omitempty"`
+}
+
+// genVercelJSON generates vercel.json. Vercel uses path-to-regexp
+// syntax so "/foo/*" becomes "/foo/:splat*".
+// Vercel applies redirects even if there's a file for the url, so we
+// skip those to match Netlify and our server. Rewrites are only
+// applied when there's no file
+func genVercelJSON(redirects []*Redirect, urlExists func(string) bool) ([]byte, error) {
+	cfg := &vercelConfig{
+		CleanURLs: true,
+	}
+	for _, r := range redirects {
+		route := &vercelRoute{
+			Source:      r.From,
+			Destination: r.To,
+		}
+		if strings.HasSuffix(r.From, "*") {
+			route.Source = strings.TrimSuffix(r.From, "*") + ":splat*"
+			route.Destination = strings.Replace(r.To, ":splat", ":splat*", -1)
+		}
+		if r.Code == http.StatusOK {
+			cfg.Rewrites = append(cfg.Rewrites, route)
+			continue
+		}
+		if !r.isPattern() && urlExists(r.From) {
+			logvf("genVercelJSON: skipping redirect '%s' => '%s' because the url exists\n", r.From, r.To)
+			continue
 		}
-		res[k] = ri
+		route.StatusCode = r.Code
+		cfg.Redirects = append(cfg.Redirects, route)
 	}
-	return res
+	return json.MarshalIndent(cfg, "", "  ")
 }
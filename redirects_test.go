@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestParseRedirectsJSON(t *testing.T) {
+	d := []byte(`{"redirects": [
+	{"from": "/blog", "to": "/"},
+	{"from": "/old/*", "to": "/new/:splat", "code": 301}
+]}`)
+	redirects, err := parseRedirectsJSON(d)
+	assert.NoError(t, err)
+	assert.Len(t, redirects, 2)
+	assert.Equal(t, 302, redirects[0].Code)
+	assert.Equal(t, 301, redirects[1].Code)
+	// patterns are compiled when loaded
+	assert.Nil(t, redirects[0].rx)
+	assert.NotNil(t, redirects[1].rx)
+
+	_, err = parseRedirectsJSON([]byte(`{"redirects": [{"from": "blog", "to": "/"}]}`))
+	assert.Error(t, err)
+	_, err = parseRedirectsJSON([]byte(`{"redirects": [{"from": "/blog", "to": "/", "code": 404}]}`))
+	assert.Error(t, err)
+}
+
+func TestFindRedirect(t *testing.T) {
+	redirects := []*Redirect{
+		newRedirect("/blog", "/", 302),
+		newRedirect("/cheatsheets/*", "https://referenceguide.dev/cheatsheet/:splat", 302),
+	}
+	r, to := findRedirect(redirects, "/blog")
+	assert.NotNil(t, r)
+	assert.Equal(t, "/", to)
+
+	r, to = findRedirect(redirects, "/cheatsheets/go.html")
+	assert.NotNil(t, r)
+	assert.Equal(t, "https://referenceguide.dev/cheatsheet/go.html", to)
+
+	r, _ = findRedirect(redirects, "/blog/")
+	assert.Nil(t, r)
+}
+
+func TestOldArticleURLsRewrite(t *testing.T) {
+	r, to := findRedirect(getAllRedirects(), "/articles/ea07db1b9bff415ab180b0525f3898f6/advanced-web-spidering.html")
+	assert.NotNil(t, r)
+	assert.Equal(t, 200, r.Code)
+	assert.Equal(t, "/articles/ea07db1b9bff415ab180b0525f3898f6.html", to)
+	r, _ = findRedirect(getAllRedirects(), "/articles/xin-chao.html")
+	assert.Nil(t, r)
+
+	txt := string(genRedirectsTxt(getAllRedirects()))
+	assert.True(t, strings.Contains(txt, "/articles/:id/* /articles/:id.html 200\n"))
+
+	d, err := genVercelJSON(getAllRedirects(), func(string) bool { return false })
+	assert.NoError(t, err)
+	var cfg vercelConfig
+	assert.NoError(t, json.Unmarshal(d, &cfg))
+	found := false
+	for _, route := range cfg.Rewrites {
+		if route.Source == "/articles/:id/:splat*" && route.Destination == "/articles/:id.html" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+	for _, route := range cfg.Redirects {
+		assert.NotEqual(t, "/articles/:id/:splat*", route.Source)
+	}
+}
+
+func TestResetArticleRedirects(t *testing.T) {
+	defer resetArticleRedirects()
+	addRedirect("/article/old.html", "/article/new.html", 301)
+	r, _ := findRedirect(getAllRedirects(), "/article/old.html")
+	assert.NotNil(t, r)
+
+	// e.g. article was renamed again before reloading
+	resetArticleRedirects()
+	r, _ = findRedirect(getAllRedirects(), "/article/old.html")
+	assert.Nil(t, r)
+}
+
+func TestValidateRedirects(t *testing.T) {
+	urlExists := func(uri string) bool {
+		return uri == "/" || uri == "/article/foo.html"
+	}
+	redirects := []*Redirect{
+		newRedirect("/a", "/b", 301),
+		newRedirect("/b", "/article/foo.html", 301),
+		newRedirect("/ext", "https://example.com", 302),
+		newRedirect("/old/*", "/new/:splat", 301),
+	}
+	assert.Empty(t, validateRedirects(redirects, urlExists))
+
+	loop := append(redirects, newRedirect("/c", "/d", 301), newRedirect("/d", "/c", 301))
+	assert.Len(t, validateRedirects(loop, urlExists), 2)
+
+	dead := append(redirects, newRedirect("/e", "/missing.html", 301))
+	assert.Len(t, validateRedirects(dead, urlExists), 1)
+
+	conflict := append(redirects, newRedirect("/a", "/", 301))
+	assert.Len(t, validateRedirects(conflict, urlExists), 1)
+}
+
+func TestRedirectsTxtRoundTrip(t *testing.T) {
+	redirects := []*Redirect{
+		newRedirect("/blog", "/", 302),
+		newRedirect("/old/*", "/new/:splat", 301),
+		newRedirect("/api/*", "/index.html", 200),
+	}
+	d := genRedirectsTxt(redirects)
+	assert.Equal(t, "/blog / 302\n/old/* /new/:splat 301\n/api/* /index.html 200\n", string(d))
+	parsed, err := parseRedirectsTxt(d)
+	assert.NoError(t, err)
+	assert.Equal(t, redirects, parsed)
+}
+
+func TestGenVercelJSON(t *testing.T) {
+	redirects := []*Redirect{
+		newRedirect("/blog", "/", 302),
+		newRedirect("/old/*", "/new/:splat", 301),
+		newRedirect("/api/*", "/index.html", 200),
+	}
+	d, err := genVercelJSON(redirects, func(string) bool { return false })
+	assert.NoError(t, err)
+	var cfg vercelConfig
+	assert.NoError(t, json.Unmarshal(d, &cfg))
+	assert.True(t, cfg.CleanURLs)
+	assert.Len(t, cfg.Redirects, 2)
+	assert.Equal(t, "/old/:splat*", cfg.Redirects[1].Source)
+	assert.Equal(t, "/new/:splat*", cfg.Redirects[1].Destination)
+	assert.Equal(t, 301, cfg.Redirects[1].StatusCode)
+	assert.Len(t, cfg.Rewrites, 1)
+	assert.Equal(t, 0, cfg.Rewrites[0].StatusCode)
+
+	// Vercel would redirect even if /blog exists, Netlify serves the file
+	d, err = genVercelJSON(redirects, func(uri string) bool { return uri == "/blog" })
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(d, &cfg))
+	assert.Len(t, cfg.Redirects, 1)
+	assert.Equal(t, "/old/:splat*", cfg.Redirects[0].Source)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// for each article we recommend other articles with similar tags and text
+
+const (
+	relatedArticlesMin = 3
+	relatedArticlesMax = 5
+	// how much a shared tag adds to the score. Text similarity is 0...1
+	relatedTagWeight = 0.25
+)
+
+// termVector maps a word to its tf-idf weight
+type termVector map[string]float64
+
+// cosine returns cosine similarity of tf-idf vectors
+func (v termVector) cosine(other termVector) float64 {
+	var dot, norm1, norm2 float64
+	for word, w := range v {
+		dot += w * other[word]
+		norm1 += w * w
+	}
+	for _, w := range other {
+		norm2 += w * w
+	}
+	if norm1 == 0 || norm2 == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(norm1) * math.Sqrt(norm2))
+}
+
+// buildTermVectors returns tf-idf vectors of texts
+func buildTermVectors(texts []string) []termVector {
+	var counts []map[string]int
+	docFreq := map[string]int{}
+	for _, text := range texts {
+		m := map[string]int{}
+		for _, tok := range tokenizeForSearch(text) {
+			// skip short words, they're mostly noise
+			if len(tok) < 3 {
+				continue
+			}
+			m[tok]++
+		}
+		for tok := range m {
+			docFreq[tok]++
+		}
+		counts = append(counts, m)
+	}
+	n := float64(len(texts))
+	var res []termVector
+	for _, m := range counts {
+		v := termVector{}
+		for tok, count := range m {
+			idf := math.Log(n / float64(docFreq[tok]))
+			if idf > 0 {
+				v[tok] = (1 + math.Log(float64(count))) * idf
+			}
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+func countSharedTags(a1, a2 *Article) int {
+	n := 0
+	for _, t1 := range a1.Tags {
+		for _, t2 := range a2.Tags {
+			if t1 == t2 {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// buildRelatedArticles sets RelatedArticles of articles. Must be called
+// after html of articles was generated. Articles that are pages
+// (e.g. about) are neither given recommendations nor recommended
+func buildRelatedArticles(articles []*Article) {
+	var candidates []*Article
+	var texts []string
+	for _, a := range articles {
+		if a.IsHidden() || a.Type == "Page" {
+			continue
+		}
+		candidates = append(candidates, a)
+		texts = append(texts, a.Title+" "+htmlToText(a.BodyHTML))
+	}
+	vectors := buildTermVectors(texts)
+
+	type scored struct {
+		article *Article
+		score   float64
+	}
+	for i, a := range candidates {
+		var arr []scored
+		for j, other := range candidates {
+			if i == j {
+				continue
+			}
+			score := vectors[i].cosine(vectors[j])
+			score += relatedTagWeight * float64(countSharedTags(a, other))
+			arr = append(arr, scored{other, score})
+		}
+		sort.SliceStable(arr, func(i, j int) bool {
+			if arr[i].score != arr[j].score {
+				return arr[i].score > arr[j].score
+			}
+			// if equally similar, prefer newer articles
+			return arr[i].article.PublishedOn.After(arr[j].article.PublishedOn)
+		})
+		a.RelatedArticles = nil
+		for _, s := range arr {
+			n := len(a.RelatedArticles)
+			if n == relatedArticlesMax || (s.score <= 0 && n >= relatedArticlesMin) {
+				break
+			}
+			a.RelatedArticles = append(a.RelatedArticles, s.article)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestBuildRelatedArticles(t *testing.T) {
+	now := time.Now().Add(-time.Hour)
+	mk := func(id string, tags []string, body string) *Article {
+		return &Article{
+			ID:          id,
+			Title:       id,
+			Type:        "Post",
+			Status:      statusPublished,
+			Tags:        tags,
+			BodyHTML:    "<p>" + body + "</p>",
+			PublishedOn: now,
+		}
+	}
+	goroutines := mk("goroutines", []string{"go"}, "goroutines and channels make concurrency easy")
+	channels := mk("channels", []string{"go"}, "buffered channels and goroutines")
+	generics := mk("generics", []string{"go"}, "type parameters in functions")
+	css := mk("css", []string{"web"}, "flexbox layout for websites")
+	html := mk("html", []string{"web"}, "semantic markup for websites")
+	draft := mk("draft", []string{"go"}, "goroutines and channels draft")
+	draft.Status = statusDraft
+	about := mk("about", nil, "goroutines channels")
+	about.Type = "Page"
+
+	articles := []*Article{goroutines, channels, generics, css, html, draft, about}
+	buildRelatedArticles(articles)
+
+	related := goroutines.RelatedArticles
+	assert.True(t, len(related) >= relatedArticlesMin && len(related) <= relatedArticlesMax)
+	assert.Equal(t, channels, related[0])
+	assert.Equal(t, generics, related[1])
+	for _, a := range related {
+		assert.NotEqual(t, goroutines, a)
+		assert.NotEqual(t, draft, a)
+		assert.NotEqual(t, about, a)
+	}
+	assert.Equal(t, html, css.RelatedArticles[0])
+	assert.Empty(t, about.RelatedArticles)
+	assert.Empty(t, draft.RelatedArticles)
+}
+
+func TestTermVectorCosine(t *testing.T) {
+	vectors := buildTermVectors([]string{"apple banana", "apple banana", "cherry"})
+	assert.True(t, vectors[0].cosine(vectors[1]) > 0.99)
+	assert.Equal(t, 0.0, vectors[0].cosine(vectors[2]))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	textPolicy = bluemonday.StrictPolicy().AddSpaceWhenStrippingTag(true)
+)
+
+// htmlToText returns text content of html, without tags
+func htmlToText(s string) string {
+	s = textPolicy.Sanitize(s)
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// normalizeForSearch lower-cases s and removes diacritics so that
+// "Việt" and "viet" match
+func normalizeForSearch(s string) string {
+	return strings.ToLower(removeDiacritics(s))
+}
+
+// tokenizeForSearch splits normalized s into words
+func tokenizeForSearch(s string) []string {
+	isSep := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+	return strings.FieldsFunc(normalizeForSearch(s), isSep)
+}
+
+// uniqueTokens returns tokens of s in order of first appearance
+// skipping duplicates and single-letter tokens
+func uniqueTokens(s string) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, tok := range tokenizeForSearch(s) {
+		if len(tok) < 2 || seen[tok] {
+			continue
+		}
+		seen[tok] = true
+		res = append(res, tok)
+	}
+	return res
+}
+
+// shortenText returns up to maxLen runes of s, cut at word boundary
+func shortenText(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	s = string(runes[:maxLen])
+	if idx := strings.LastIndex(s, " "); idx > 0 {
+		s = s[:idx]
+	}
+	return s + "…"
+}
+
+// articleSummary returns summary of the article, falling back to
+// the beginning of its text
+func articleSummary(a *Article, text string) string {
+	if a.Summary != "" {
+		return a.Summary
+	}
+	if a.Description != "" {
+		return a.Description
+	}
+	return shortenText(text, 160)
+}
+
+// SearchDoc is an entry in search index. Uses short json names
+// to keep the index small
+type SearchDoc struct {
+	Title   string   `json:"t"`
+	URL     string   `json:"u"`
+	Summary string   `json:"s,omitempty"`
+	Tags    []string `json:"g,omitempty"`
+	// normalized, space-separated unique words of title and body
+	Words string `json:"w"`
+}
+
+func searchableArticles(store *Articles) []*Article {
+	var res []*Article
+	for _, a := range store.articles {
+		if a.IsHidden() {
+			continue
+		}
+		res = append(res, a)
+	}
+	return res
+}
+
+func buildSearchDocs(articles []*Article) []*SearchDoc {
+	var res []*SearchDoc
+	for _, a := range articles {
+		text := htmlToText(a.BodyHTML)
+		words := uniqueTokens(a.Title + " " + strings.Join(a.Tags, " ") + " " + text)
+		doc := &SearchDoc{
+			Title:   a.Title,
+			URL:     a.URL(),
+			Summary: articleSummary(a, text),
+			Tags:    a.Tags,
+			Words:   strings.Join(words, " "),
+		}
+		res = append(res, doc)
+	}
+	return res
+}
+
+// genSearchIndex generates /search-index.json used by /search.html
+func genSearchIndex(store *Articles) ([]byte, error) {
+	docs := buildSearchDocs(searchableArticles(store))
+	return json.Marshal(docs)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	searchMaxResults   = 20
+	searchSnippetWords = 12
+)
+
+type searchPosting struct {
+	docIdx int
+	// how many times the word appears, weighted by where (title, tags, body)
+	weight float64
+}
+
+// searchIndex is an in-memory inverted index of articles
+type searchIndex struct {
+	docs []*SearchDoc
+	// plain text of the articles, used for snippets
+	texts []string
+	// normalized word => documents that contain it
+	postings map[string][]searchPosting
+	// sorted keys of postings, for prefix search
+	words []string
+}
+
+// SearchResult is a single result returned by /api/search
+type SearchResult struct {
+	Title   string   `json:"title"`
+	URL     string   `json:"url"`
+	Summary string   `json:"summary,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Score   float64  `json:"score"`
+	// html with matched words wrapped in <mark>
+	Snippet string `json:"snippet,omitempty"`
+}
+
+func newSearchIndex(docs []*SearchDoc, texts []string) *searchIndex {
+	idx := &searchIndex{
+		docs:     docs,
+		texts:    texts,
+		postings: map[string][]searchPosting{},
+	}
+	for i, doc := range docs {
+		weights := map[string]float64{}
+		for _, tok := range tokenizeForSearch(doc.Title) {
+			weights[tok] += 5
+		}
+		for _, tag := range doc.Tags {
+			for _, tok := range tokenizeForSearch(tag) {
+				weights[tok] += 3
+			}
+		}
+		for _, tok := range tokenizeForSearch(texts[i]) {
+			weights[tok]++
+		}
+		for tok, w := range weights {
+			p := searchPosting{docIdx: i, weight: w}
+			idx.postings[tok] = append(idx.postings[tok], p)
+		}
+	}
+	for word := range idx.postings {
+		idx.words = append(idx.words, word)
+	}
+	sort.Strings(idx.words)
+	return idx
+}
+
+func newSearchIndexFromArticles(articles []*Article) *searchIndex {
+	docs := buildSearchDocs(articles)
+	var texts []string
+	for _, a := range articles {
+		texts = append(texts, htmlToText(a.BodyHTML))
+	}
+	return newSearchIndex(docs, texts)
+}
+
+// newSearchIndexFromDir builds the index from search-index.json and
+// html files generated by -gen
+func newSearchIndexFromDir(dir string) (*searchIndex, error) {
+	var docs []*SearchDoc
+	d, err := ioutil.ReadFile(filepath.Join(dir, "search-index.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(d, &docs); err != nil {
+		return nil, err
+	}
+	var texts []string
+	for _, doc := range docs {
+		text := doc.Summary
+		path := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(doc.URL, "/")))
+		if fileExists(path) {
+			text = htmlToText(string(readFileMust(path)))
+		}
+		texts = append(texts, text)
+	}
+	return newSearchIndex(docs, texts), nil
+}
+
+// returns postings for the word. If there are no exact matches, we
+// treat the word as a prefix e.g. "kube" matches "kubernetes"
+func (idx *searchIndex) lookup(word string) []searchPosting {
+	if res := idx.postings[word]; len(res) > 0 || len(word) < 3 {
+		return res
+	}
+	var res []searchPosting
+	i := sort.SearchStrings(idx.words, word)
+	for ; i < len(idx.words) && strings.HasPrefix(idx.words[i], word); i++ {
+		// prefix matches are worth less than exact matches
+		for _, p := range idx.postings[idx.words[i]] {
+			p.weight /= 2
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Search returns articles that contain all words of the query, best first
+func (idx *searchIndex) Search(query string) []*SearchResult {
+	words := tokenizeForSearch(query)
+	if len(words) == 0 {
+		return nil
+	}
+	nDocs := float64(len(idx.docs))
+	var scores map[int]float64
+	for _, word := range words {
+		matched := map[int]float64{}
+		for _, p := range idx.lookup(word) {
+			matched[p.docIdx] += p.weight
+		}
+		idf := math.Log(1 + nDocs/float64(1+len(matched)))
+		next := map[int]float64{}
+		for docIdx, w := range matched {
+			// all words must match
+			if _, ok := scores[docIdx]; scores != nil && !ok {
+				continue
+			}
+			next[docIdx] = scores[docIdx] + (1+math.Log(w))*idf
+		}
+		scores = next
+	}
+
+	var res []*SearchResult
+	for docIdx, score := range scores {
+		doc := idx.docs[docIdx]
+		r := &SearchResult{
+			Title:   doc.Title,
+			URL:     doc.URL,
+			Summary: doc.Summary,
+			Tags:    doc.Tags,
+			Score:   math.Round(score*1000) / 1000,
+			Snippet: searchSnippet(idx.texts[docIdx], words),
+		}
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].Title < res[j].Title
+	})
+	if len(res) > searchMaxResults {
+		res = res[:searchMaxResults]
+	}
+	return res
+}
+
+type wordPos struct {
+	start int
+	end   int
+}
+
+// splitWordPositions returns byte offsets of words in s
+func splitWordPositions(s string) []wordPos {
+	var res []wordPos
+	start := -1
+	for i, r := range s {
+		isWordChar := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if isWordChar && start == -1 {
+			start = i
+		} else if !isWordChar && start != -1 {
+			res = append(res, wordPos{start, i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		res = append(res, wordPos{start, len(s)})
+	}
+	return res
+}
+
+func isSearchMatch(word string, queryWords []string) bool {
+	word = normalizeForSearch(word)
+	for _, q := range queryWords {
+		if word == q || (len(q) >= 3 && strings.HasPrefix(word, q)) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchSnippet returns html fragment of text around the first match
+// with matched words wrapped in <mark>
+func searchSnippet(text string, queryWords []string) string {
+	words := splitWordPositions(text)
+	first := -1
+	for i, w := range words {
+		if isSearchMatch(text[w.start:w.end], queryWords) {
+			first = i
+			break
+		}
+	}
+	if first == -1 {
+		return ""
+	}
+	startIdx := first - searchSnippetWords/2
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + searchSnippetWords*2
+	if endIdx > len(words) {
+		endIdx = len(words)
+	}
+
+	var sb strings.Builder
+	if startIdx > 0 {
+		sb.WriteString("… ")
+	}
+	pos := words[startIdx].start
+	for _, w := range words[startIdx:endIdx] {
+		sb.WriteString(html.EscapeString(text[pos:w.start]))
+		word := html.EscapeString(text[w.start:w.end])
+		if isSearchMatch(text[w.start:w.end], queryWords) {
+			word = "<mark>" + word + "</mark>"
+		}
+		sb.WriteString(word)
+		pos = w.end
+	}
+	if endIdx < len(words) {
+		sb.WriteString(" …")
+	}
+	return sb.String()
+}
+
+var (
+	allSearchIndex *searchIndex
+)
+
+// /api/search?q=${query}
+func handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	if allSearchIndex == nil {
+		http.NotFound(w, r)
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	results := allSearchIndex.Search(q)
+	if results == nil {
+		results = []*SearchResult{}
+	}
+	rsp := struct {
+		Query   string          `json:"query"`
+		Results []*SearchResult `json:"results"`
+	}{
+		Query:   q,
+		Results: results,
+	}
+	httpOkWithJSON(w, r, rsp)
+}
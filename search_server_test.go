@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestSearchIndex(t *testing.T) {
+	articles := []*Article{
+		{
+			Title:    "Cài đặt Kubernetes",
+			Tags:     []string{"devops"},
+			BodyHTML: "<p>Hướng dẫn cài đặt cluster Kubernetes trên máy ảo.</p>",
+		},
+		{
+			Title:    "Pagination in NestJS",
+			Tags:     []string{"nestjs"},
+			BodyHTML: "<p>Deploying NestJS on kubernetes is not covered here.</p>",
+		},
+	}
+	idx := newSearchIndexFromArticles(articles)
+
+	res := idx.Search("kubernetes")
+	assert.Equal(t, 2, len(res))
+	// match in the title ranks higher
+	assert.Equal(t, "Cài đặt Kubernetes", res[0].Title)
+
+	// no diacritics in the query
+	res = idx.Search("cai dat")
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "Hướng dẫn <mark>cài</mark> <mark>đặt</mark> cluster Kubernetes trên máy ảo", res[0].Snippet)
+
+	// prefix match
+	res = idx.Search("nest")
+	assert.Equal(t, 1, len(res))
+
+	// all words must match
+	res = idx.Search("kubernetes nestjs")
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "/articles/pagination-in-nestjs.html", res[0].URL)
+
+	assert.Equal(t, 0, len(idx.Search("")))
+}
+
+func TestHandleAPISearch(t *testing.T) {
+	prev := allSearchIndex
+	defer func() {
+		allSearchIndex = prev
+	}()
+	allSearchIndex = newSearchIndexFromArticles([]*Article{{Title: "Hello World", BodyHTML: "<p>hi</p>"}})
+
+	r := httptest.NewRequest("GET", "/api/search?q=hello", nil)
+	w := httptest.NewRecorder()
+	handleAPISearch(w, r)
+	assert.Equal(t, 200, w.Code)
+	var rsp struct {
+		Query   string
+		Results []*SearchResult
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+	assert.Equal(t, "hello", rsp.Query)
+	assert.Equal(t, 1, len(rsp.Results))
+	assert.Equal(t, "Hello World", rsp.Results[0].Title)
+}
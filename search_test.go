@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestHTMLToText(t *testing.T) {
+	s := `<p>Hello <b>world</b></p><p>second&nbsp;para &amp; more</p><script>var x = 1;</script>`
+	got := htmlToText(s)
+	assert.Equal(t, "Hello world second para & more", got)
+}
+
+func TestTokenizeForSearch(t *testing.T) {
+	got := tokenizeForSearch("Đường đến Kubernetes, phần 2!")
+	assert.Equal(t, []string{"duong", "den", "kubernetes", "phan", "2"}, got)
+
+	words := uniqueTokens("Go go GO, gỏ a b")
+	assert.Equal(t, []string{"go"}, words)
+}
+
+// normalize() in search.tmpl.html must match normalizeForSearch()
+var normalizeForSearchTests = []struct {
+	s   string
+	exp string
+}{
+	{"Tiếng Việt", "tieng viet"},
+	{"Đà Nẵng", "da nang"},
+	{"Straße", "strasse"},
+	{"Łódź", "lodz"},
+	{"Ærø", "aero"},
+	{"Crème Brûlée", "creme brulee"},
+}
+
+func TestNormalizeForSearch(t *testing.T) {
+	for _, test := range normalizeForSearchTests {
+		assert.Equal(t, test.exp, normalizeForSearch(test.s))
+	}
+}
+
+func TestNormalizeForSearchJS(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, genSearch(nil, &buf))
+	m := regexp.MustCompile(`(?s)<script type="text/javascript">(.*?)</script>`).FindSubmatch(buf.Bytes())
+	assert.NotNil(t, m)
+
+	var inputs []string
+	for _, test := range normalizeForSearchTests {
+		inputs = append(inputs, test.s)
+	}
+	d, err := json.Marshal(inputs)
+	assert.NoError(t, err)
+	js := "var document = { addEventListener: function () {} };\n" + string(m[1]) +
+		"\nconsole.log(JSON.stringify(" + string(d) + ".map(normalize)));"
+	out, err := exec.Command(node, "-e", js).Output()
+	assert.NoError(t, err)
+	var got []string
+	assert.NoError(t, json.Unmarshal(out, &got))
+	for i, test := range normalizeForSearchTests {
+		assert.Equal(t, test.exp, got[i])
+	}
+}
+
+func TestBuildSearchDocs(t *testing.T) {
+	a := &Article{
+		Title:    "Cài đặt Kubernetes",
+		Tags:     []string{"devops"},
+		BodyHTML: "<p>Hướng dẫn cài đặt cluster</p>",
+	}
+	docs := buildSearchDocs([]*Article{a})
+	assert.Equal(t, 1, len(docs))
+	doc := docs[0]
+	assert.Equal(t, "/articles/cai-dat-kubernetes.html", doc.URL)
+	assert.Equal(t, "Hướng dẫn cài đặt cluster", doc.Summary)
+	assert.True(t, strings.Contains(" "+doc.Words+" ", " huong "))
+	assert.True(t, strings.Contains(" "+doc.Words+" ", " devops "))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Series is a multi-part article, made of articles with the same
+// Series property
+type Series struct {
+	Name string
+	Slug string
+	// ordered by SeriesOrder, then PublishedOn
+	Articles []*Article
+}
+
+// URL returns url of the index page of the series
+func (s *Series) URL() string {
+	return "/series/" + s.Slug + ".html"
+}
+
+// partIndex returns index of article in the series or -1
+func (s *Series) partIndex(a *Article) int {
+	for i, sa := range s.Articles {
+		if sa == a {
+			return i
+		}
+	}
+	return -1
+}
+
+// isSeriesName returns true if s looks like a name of a series and not
+// like a sentence, so that an opening paragraph "Series: in this post..."
+// isn't taken for "series:" metadata
+func isSeriesName(s string) bool {
+	if s == "" || strings.Contains(s, "\n") || len(strings.Fields(s)) > 8 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return !strings.ContainsRune(".,:;!?…", r)
+}
+
+// parseSeriesOrder parses order index like "2" or "#2". Returns 0 if
+// not a valid index
+func parseSeriesOrder(s string) int {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// buildSeries groups articles by their Series and links articles to
+// their series
+func buildSeries(articles []*Article) []*Series {
+	slugToSeries := map[string]*Series{}
+	var res []*Series
+	for _, a := range articles {
+		name := strings.TrimSpace(a.Series)
+		if name == "" {
+			continue
+		}
+		slug := slugify(name)
+		if slug == "" {
+			logf(ctx(), "buildSeries: article %s has series '%s' that can't be turned into url\n", a.ID, name)
+			continue
+		}
+		s := slugToSeries[slug]
+		if s == nil {
+			s = &Series{
+				Name: name,
+				Slug: slug,
+			}
+			slugToSeries[slug] = s
+			res = append(res, s)
+		}
+		s.Articles = append(s.Articles, a)
+		a.series = s
+	}
+	for _, s := range res {
+		arr := s.Articles
+		sort.Slice(arr, func(i, j int) bool {
+			a1 := arr[i]
+			a2 := arr[j]
+			if a1.SeriesOrder != a2.SeriesOrder {
+				// articles without order go last
+				if a1.SeriesOrder == 0 || a2.SeriesOrder == 0 {
+					return a2.SeriesOrder == 0
+				}
+				return a1.SeriesOrder < a2.SeriesOrder
+			}
+			if !a1.PublishedOn.Equal(a2.PublishedOn) {
+				return a1.PublishedOn.Before(a2.PublishedOn)
+			}
+			return a1.ID < a2.ID
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Slug < res[j].Slug
+	})
+	return res
+}
+
+// /series/${slug}.html
+func genSeries(series *Series, w io.Writer) error {
+	model := struct {
+		Article *Article
+		Series  *Series
+		FeedURL string
+	}{
+		Series:  series,
+		FeedURL: topicFeedURL(series.URL()),
+	}
+	return execTemplate(series.URL(), "series.tmpl.html", model, w)
+}
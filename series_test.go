@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+)
+
+func TestBuildSeries(t *testing.T) {
+	t1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	p1 := &Article{ID: "p1", Title: "Part one", Series: "Học Go", SeriesOrder: 1, PublishedOn: t1.Add(48 * time.Hour)}
+	p2 := &Article{ID: "p2", Title: "Part two", Series: "Học Go", SeriesOrder: 2, PublishedOn: t1}
+	p3 := &Article{ID: "p3", Title: "Extra", Series: "học go ", PublishedOn: t1}
+	other := &Article{ID: "o", Title: "Other"}
+	series := buildSeries([]*Article{p3, other, p2, p1})
+	assert.Equal(t, 1, len(series))
+	s := series[0]
+	assert.Equal(t, "/series/hoc-go.html", s.URL())
+	assert.Equal(t, []*Article{p1, p2, p3}, s.Articles)
+	assert.Equal(t, s, p3.series)
+	assert.True(t, other.series == nil)
+	assert.Equal(t, 1, s.partIndex(p2))
+
+	assert.Equal(t, 3, parseSeriesOrder(" #3"))
+	assert.Equal(t, 0, parseSeriesOrder("three"))
+}
+
+func TestGenArticleSeries(t *testing.T) {
+	p1 := &Article{ID: "p1", Title: "Part one", Slug: "part-one", Series: "Go", SeriesOrder: 1}
+	p2 := &Article{ID: "p2", Title: "Part two", Slug: "part-two", Series: "Go", SeriesOrder: 2}
+	buildSeries([]*Article{p1, p2})
+	var buf bytes.Buffer
+	err := genArticle(p2, &buf)
+	assert.NoError(t, err)
+	s := buf.String()
+	assert.True(t, strings.Contains(s, `Part 2 of <a href="/series/go.html">Go</a>`))
+	assert.True(t, strings.Contains(s, `<a href="/articles/part-one.html">← Part one</a>`))
+
+	buf.Reset()
+	err = genSeries(p1.series, &buf)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(buf.String(), `<a href="/articles/part-two.html">Part two</a>`))
+}
+
+func TestParseSeriesMeta(t *testing.T) {
+	textBlock := func(s string) *notionapi.Block {
+		return &notionapi.Block{
+			Type:          notionapi.BlockText,
+			InlineContent: []*notionapi.TextSpan{{Text: s}},
+		}
+	}
+	a := &Article{}
+	assert.True(t, a.maybeParseMeta(0, textBlock("series: Học Go")))
+	assert.Equal(t, "Học Go", a.Series)
+
+	// ordinary body text is not metadata
+	for _, s := range []string{
+		"Series: in this post I explain how goroutines are scheduled.",
+		"Series: this is the first part of a long series about Go",
+		"Series: to be continued…",
+	} {
+		a = &Article{}
+		assert.False(t, a.maybeParseMeta(0, textBlock(s)))
+		assert.Equal(t, "", a.Series)
+	}
+}
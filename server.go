@@ -2,10 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/felixge/httpsnoop"
@@ -16,6 +18,18 @@ var (
 	allArticles *Articles
 	allTagURLS  []string // first item is tag, second is its url
 	articleURLS []string // the order is the same as allArticles.articles
+	previewURLS []string // the order is the same as allArticles.previews
+	// pages of index, archives and tag archives
+	allListPages []*listPage
+	// atom feeds of tags, series and collections
+	allTopicFeeds []*topicFeed
+	// parts of sitemap, if it's too big for a single file
+	siteMapPartURLS []string
+	// set by makeDynamicServer, to check which urls exist
+	siteServer *server.Server
+
+	// protects the above when articles are reloaded by -run-dev
+	articlesMu sync.RWMutex
 )
 
 func tryServeFile(uri string, dir string) func(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +52,15 @@ func serveImage(uri string) func(w http.ResponseWriter, r *http.Request) {
 	return tryServeFile(uri, dir)
 }
 
+func serveMedia(uri string) func(w http.ResponseWriter, r *http.Request) {
+	uri = strings.TrimPrefix(uri, "/media/")
+	if !isMediaFile(uri) {
+		return nil
+	}
+	dir := filepath.Join("notion_cache", "files")
+	return tryServeFile(uri, dir)
+}
+
 func serveStart(w http.ResponseWriter, r *http.Request, uri string) {
 	if r == nil {
 		return
@@ -53,6 +76,9 @@ func serverGet(uri string) func(w http.ResponseWriter, r *http.Request) {
 	if strings.HasPrefix(uri, "/img/") {
 		return serveImage(uri)
 	}
+	if strings.HasPrefix(uri, "/media/") {
+		return serveMedia(uri)
+	}
 	if serve := tryServeFile(uri, "www"); serve != nil {
 		return serve
 	}
@@ -62,31 +88,35 @@ func serverGet(uri string) func(w http.ResponseWriter, r *http.Request) {
 		must(err)
 	}
 	switch uri {
-	case "/index.html":
+	case "/changelog.html":
 		return func(w http.ResponseWriter, r *http.Request) {
-			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "genIndex")
+			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "genChangelog")
 			serveStart(w, r, uri)
-			genIndex(store, w)
+			genChangelog(store, w)
 		}
-	case "/archives.html":
+	case "/sitemap.xml":
 		return func(w http.ResponseWriter, r *http.Request) {
-			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "writeArticlesArchiveForTag")
+			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "genSiteMap")
 			serveStart(w, r, uri)
-			writeArticlesArchiveForTag(store, "", w)
+			d, err := genSiteMap(store, getHostURL())
+			writeData(w, d, err)
 		}
-	case "/changelog.html":
+	case "/_redirects":
 		return func(w http.ResponseWriter, r *http.Request) {
-			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "genChangelog")
 			serveStart(w, r, uri)
-			genChangelog(store, w)
+			writeData(w, genRedirectsTxt(getAllRedirects()), nil)
 		}
-	case "/sitemap.xml":
+	case "/vercel.json":
 		return func(w http.ResponseWriter, r *http.Request) {
-			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "genSiteMap")
 			serveStart(w, r, uri)
-			d, err := genSiteMap(store, "https://ntheanh201.vercel.app")
+			d, err := genVercelJSON(getAllRedirects(), newURLExists(siteServer))
 			writeData(w, d, err)
 		}
+	case "/robots.txt":
+		return func(w http.ResponseWriter, r *http.Request) {
+			serveStart(w, r, uri)
+			writeData(w, genRobotsTxt(getHostURL()), nil)
+		}
 	case "/atom.xml":
 		return func(w http.ResponseWriter, r *http.Request) {
 			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "genAtomXML")
@@ -101,19 +131,42 @@ func serverGet(uri string) func(w http.ResponseWriter, r *http.Request) {
 			d, err := genAtomXML(store, false)
 			writeData(w, d, err)
 		}
+	case "/rss.xml":
+		return func(w http.ResponseWriter, r *http.Request) {
+			serveStart(w, r, uri)
+			d, err := genRSSXML(store, true)
+			writeData(w, d, err)
+		}
+	case "/feed.json":
+		return func(w http.ResponseWriter, r *http.Request) {
+			serveStart(w, r, uri)
+			d, err := genJSONFeed(store, true)
+			writeData(w, d, err)
+		}
 	case "/404.html":
 		return func(w http.ResponseWriter, r *http.Request) {
 			//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "gen404")
 			serveStart(w, r, uri)
 			gen404(store, w)
 		}
+	case "/search.html":
+		return func(w http.ResponseWriter, r *http.Request) {
+			serveStart(w, r, uri)
+			genSearch(store, w)
+		}
+	case "/search-index.json":
+		return func(w http.ResponseWriter, r *http.Request) {
+			serveStart(w, r, uri)
+			d, err := genSearchIndex(store)
+			writeData(w, d, err)
+		}
 	}
 
 	n := len(articleURLS)
 	//uriLC := strings.ToLower(uri)
 	for i := 0; i < n; i++ {
 		if uri == articleURLS[i] {
-			article := allArticles.articles[i]
+			article := store.articles[i]
 			return func(w http.ResponseWriter, r *http.Request) {
 				//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "genArticle")
 				serveStart(w, r, uri)
@@ -122,15 +175,68 @@ func serverGet(uri string) func(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	n = len(allTagURLS)
-	for i := 0; i < n; i += 2 {
-		tagURL := allTagURLS[i+1]
-		if uri == tagURL {
-			tag := allTagURLS[i]
+	for i, previewURL := range previewURLS {
+		if uri == previewURL {
+			article := store.previews[i]
 			return func(w http.ResponseWriter, r *http.Request) {
-				//logf(ctx(), "serverGet: will serve '%s' with '%s'\n", uri, "writeArticlesArchiveForTag")
 				serveStart(w, r, uri)
-				writeArticlesArchiveForTag(allArticles, tag, w)
+				genArticle(article, w)
+			}
+		}
+	}
+
+	for _, c := range store.collections {
+		if uri == c.URL {
+			c := c
+			return func(w http.ResponseWriter, r *http.Request) {
+				serveStart(w, r, uri)
+				genCollection(c, w)
+			}
+		}
+	}
+
+	for _, series := range store.series {
+		if uri == series.URL() {
+			series := series
+			return func(w http.ResponseWriter, r *http.Request) {
+				serveStart(w, r, uri)
+				genSeries(series, w)
+			}
+		}
+	}
+
+	for i, partURL := range siteMapPartURLS {
+		if uri == partURL {
+			n := i + 1
+			return func(w http.ResponseWriter, r *http.Request) {
+				serveStart(w, r, uri)
+				d, err := genSiteMapPart(store, getHostURL(), n)
+				writeData(w, d, err)
+			}
+		}
+	}
+
+	for _, f := range allTopicFeeds {
+		if uri == f.URL {
+			f := f
+			return func(w http.ResponseWriter, r *http.Request) {
+				serveStart(w, r, uri)
+				d, err := genTopicFeed(f)
+				writeData(w, d, err)
+			}
+		}
+	}
+
+	for _, lp := range allListPages {
+		if uri == lp.URL {
+			lp := lp
+			return func(w http.ResponseWriter, r *http.Request) {
+				serveStart(w, r, uri)
+				if lp.isIndex {
+					genIndex(store, lp.page, w)
+				} else {
+					writeArticlesArchiveForTag(store, lp.tag, lp.page, w)
+				}
 			}
 		}
 	}
@@ -139,82 +245,155 @@ func serverGet(uri string) func(w http.ResponseWriter, r *http.Request) {
 
 func serverURLS() []string {
 	files := []string{
-		"/index.html",
-		"/archives.html",
 		"/changelog.html",
 		"/sitemap.xml",
+		"/robots.txt",
+		"/_redirects",
+		"/vercel.json",
 		"/atom.xml",
 		"/atom-all.xml",
+		"/rss.xml",
+		"/feed.json",
 		"/404.html",
+		"/search.html",
+		"/search-index.json",
 	}
 	files = append(files, articleURLS...)
-	n := len(allTagURLS)
-	for i := 0; i < n; i += 2 {
-		tagURL := allTagURLS[i+1]
-		files = append(files, tagURL)
+	files = append(files, previewURLS...)
+	for _, c := range allArticles.collections {
+		files = append(files, c.URL)
+	}
+	for _, series := range allArticles.series {
+		files = append(files, series.URL())
+	}
+	for _, lp := range allListPages {
+		files = append(files, lp.URL)
+	}
+	for _, f := range allTopicFeeds {
+		files = append(files, f.URL)
 	}
+	files = append(files, siteMapPartURLS...)
 	return files
 }
 
 func makeDynamicServer() *server.Server {
 	loadTemplates()
+	loadFileRedirectsMust()
 
 	serveAll := server.NewDynamicHandler(serverGet, serverURLS)
 
 	// TODO: filter out templates etc.
 	serveWWW := server.NewDirHandler("www", "/", nil)
-	serveNotionImages := server.NewDirHandler(filepath.Join("notion_cache", "files"), "/img", nil)
+	notionFilesDir := filepath.Join("notion_cache", "files")
+	isImage := func(path string) bool {
+		return !isMediaFile(path)
+	}
+	serveNotionImages := server.NewDirHandler(notionFilesDir, "/img", isImage)
+	serveNotionMedia := server.NewDirHandler(notionFilesDir, "/media", isMediaFile)
 
 	server := &server.Server{
-		Handlers:  []server.Handler{serveWWW, serveNotionImages, serveAll},
+		Handlers:  []server.Handler{serveWWW, serveNotionImages, serveNotionMedia, serveAll},
 		Port:      httpPort,
 		CleanURLS: true,
 	}
+	siteServer = server
 
 	cc := getNotionCachingClient()
-	allArticles = loadArticles(cc)
+	setAllArticles(loadArticles(cc))
 	logf(ctx(), "got %d articles\n", len(allArticles.articles))
+	return server
+}
 
-	store := allArticles
-	tags := map[string]struct{}{}
-	for _, article := range store.getBlogNotHidden() {
-		for _, tag := range article.Tags {
-			tags[tag] = struct{}{}
-		}
-	}
-	for tag := range tags {
-		tagURL := "/tag/" + tag + ".html" // TODO: URL-escape?
-		allTagURLS = append(allTagURLS, tag, tagURL)
+// setAllArticles makes store the content served by the dynamic server.
+// Caller must hold articlesMu if the server is already running
+func setAllArticles(store *Articles) {
+	var tagURLS, artURLS, prevURLS []string
+	sortedTags := blogTags(store)
+	for _, tag := range sortedTags {
+		tagURLS = append(tagURLS, tag, tagURL(tag))
 	}
 	for _, article := range store.articles {
 		uri := article.URL()
-		articleURLS = append(articleURLS, uri)
+		artURLS = append(artURLS, uri)
 	}
-	return server
+	for _, article := range store.previews {
+		prevURLS = append(prevURLS, article.PreviewURL())
+	}
+	allArticles = store
+	allListPages = buildListPages(store, sortedTags)
+	allTopicFeeds = buildTopicFeeds(store)
+	siteMapPartURLS = nil
+	nParts := numSiteMapParts(len(buildSiteMapURLs(store, getHostURL())))
+	for i := 1; i <= nParts; i++ {
+		siteMapPartURLS = append(siteMapPartURLS, siteMapPartURL(i))
+	}
+	previewURLS = prevURLS
+	allTagURLS = tagURLS
+	articleURLS = artURLS
+	// buildTags() caches tags of the articles
+	allTags = nil
+	allSearchIndex = newSearchIndexFromArticles(searchableArticles(store))
 }
 
-func genHTMLServer(dir string) {
-	os.RemoveAll(dirWwwGenerated)
+// genHTMLServer generates static version of the website in dir.
+// If incremental is false, we re-generate everything from scratch
+func genHTMLServer(dir string, incremental bool) {
+	if !incremental {
+		os.RemoveAll(dir)
+	}
 	regenMd()
 	srv := makeDynamicServer()
-	nFiles := 0
-	totalSize := int64(0)
-	onWritten := func(path string, d []byte) {
-		fsize := int64(len(d))
-		totalSize += fsize
-		sizeStr := formatSize(fsize)
-		if nFiles%256 == 0 {
-			logf(ctx(), "generateStatic: file %d '%s' of size %s\n", nFiles+1, path, sizeStr)
+	urlToArticle := map[string]*Article{}
+	for i, uri := range articleURLS {
+		urlToArticle[uri] = allArticles.articles[i]
+	}
+	for i, uri := range previewURLS {
+		urlToArticle[uri] = allArticles.previews[i]
+	}
+	validateRedirectsMust(srv)
+	depsHash := articlesDepsHash(allArticles.articles)
+	stats := writeServerFilesIncremental(dir, srv.Handlers, urlToArticle, depsHash)
+	logGenStats(stats)
+}
+
+// newURLExists returns a function that checks if srv serves a url
+func newURLExists(srv *server.Server) func(string) bool {
+	urls := map[string]bool{}
+	if srv == nil {
+		return func(string) bool { return false }
+	}
+	for _, h := range srv.Handlers {
+		for _, uri := range h.URLS() {
+			urls[uri] = true
 		}
-		nFiles++
 	}
-	server.WriteServerFilesToDir(dirWwwGenerated, srv.Handlers, onWritten)
+	return func(uri string) bool {
+		if idx := strings.IndexAny(uri, "?#"); idx != -1 {
+			uri = uri[:idx]
+		}
+		if strings.HasSuffix(uri, "/") {
+			uri += "index.html"
+		}
+		// clean urls i.e. /foo serves /foo.html
+		return urls[uri] || urls[uri+".html"]
+	}
+}
+
+// validateRedirectsMust panics if redirects loop or point to urls
+// that we don't generate
+func validateRedirectsMust(srv *server.Server) {
+	errs := validateRedirects(getAllRedirects(), newURLExists(srv))
+	for _, err := range errs {
+		logerrf(ctx(), "validateRedirects: %s\n", err)
+	}
+	panicIf(len(errs) > 0, "found %d invalid redirects", len(errs))
 }
 
 func runServerDev() {
 	logf(ctx(), "runServerDev\n")
 
 	srv := makeDynamicServer()
+	startLiveReload()
 
 	closeHTTPLog := OpenHTTPLog("blog")
 	defer closeHTTPLog()
@@ -231,6 +410,18 @@ func runServerDev() {
 func runServerProd() {
 	panicIf(!dirExists(dirWwwGenerated))
 	h := server.NewDirHandler(dirWwwGenerated, "/", nil)
+	// generated by -gen, has all redirects, including those for articles
+	d, err := ioutil.ReadFile(filepath.Join(dirWwwGenerated, "_redirects"))
+	if err == nil {
+		fileRedirects, err = parseRedirectsTxt(d)
+	}
+	if err != nil {
+		logerrf(ctx(), "runServerProd: failed to read redirects with '%s'\n", err)
+	}
+	allSearchIndex, err = newSearchIndexFromDir(dirWwwGenerated)
+	if err != nil {
+		logerrf(ctx(), "runServerProd: newSearchIndexFromDir('%s') failed with '%s'\n", dirWwwGenerated, err)
+	}
 	logf(ctx(), "runServerProd starting, hasSpacesCreds: %v, %d urls\n", hasSpacesCreds(), len(h.URLS()))
 	srv := &server.Server{
 		Handlers:  []server.Handler{h},
@@ -246,14 +437,10 @@ func runServerProd() {
 	if isWindows() {
 		openBrowser(fmt.Sprintf("http://%s", httpSrv.Addr))
 	}
-	err := httpSrv.ListenAndServe()
+	err = httpSrv.ListenAndServe()
 	logf(ctx(), "runServerProd: httpSrv.ListenAndServe() returned '%s'\n", err)
 }
 
-var (
-	prefixRedirects []string
-)
-
 func makeHTTPServer(srv *server.Server) *http.Server {
 	panicIf(srv == nil, "must provide srv")
 	httpPort := 8080
@@ -265,7 +452,8 @@ func makeHTTPServer(srv *server.Server) *http.Server {
 		httpAddr = "localhost" + httpAddr
 	}
 
-	redirects := readRedirectsJSON()
+	// e.g. "/ntheanh201.vercel.app"
+	hostPrefix := "/" + strings.TrimPrefix(strings.TrimPrefix(getHostURL(), "https://"), "http://")
 
 	mainHandler := func(w http.ResponseWriter, r *http.Request) {
 		//logf(ctx(), "mainHandler: '%s'\n", r.RequestURI)
@@ -276,24 +464,22 @@ func makeHTTPServer(srv *server.Server) *http.Server {
 			}
 
 			// noticed those urls in logs
-			if strings.HasPrefix(uri, "/ntheanh201.vercel.app/") {
-				newURI := strings.TrimPrefix(uri, "/ntheanh201.vercel.app")
+			if strings.HasPrefix(uri, hostPrefix+"/") {
+				newURI := strings.TrimPrefix(uri, hostPrefix)
 				ref := r.Header.Get("Referer")
 				logf(ctx(), "redirecting '%s' => '%s', referer: '%s'\n", uri, newURI, ref)
 				http.Redirect(w, r, newURI, http.StatusTemporaryRedirect)
 				return true
 			}
 
-			if ri, ok := redirects[uri]; ok {
-				http.Redirect(w, r, ri.URL, ri.Code)
-				return true
-			}
-
-			for i := 0; i < len(prefixRedirects); i += 2 {
-				prefix := prefixRedirects[i]
-				if strings.HasPrefix(uri, prefix) {
-					redirectURL := prefixRedirects[i+1]
-					http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
+			if rd, to := findRedirect(getAllRedirects(), uri); rd != nil {
+				if rd.Code != http.StatusOK {
+					http.Redirect(w, r, to, rd.Code)
+					return true
+				}
+				// rewrite i.e. serve content of another url
+				if serve, is404 := srv.FindHandler(to); serve != nil && !is404 {
+					serve(w, r)
 					return true
 				}
 			}
@@ -303,12 +489,17 @@ func makeHTTPServer(srv *server.Server) *http.Server {
 
 		uri := r.URL.Path
 
-		if strings.HasPrefix(uri, "/cheatsheets/") {
-			redirectURL := "https://referenceguide.dev" + strings.Replace(uri, "/cheatsheets/", "/cheatsheet/", -1)
-			if uri == "/cheatsheets/" {
-				redirectURL = "https://referenceguide.dev"
-			}
-			http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
+		// long-lived, must not hold articlesMu
+		if uri == liveReloadURL && liveReloadEnabled {
+			handleLiveReload(w, r)
+			return
+		}
+
+		articlesMu.RLock()
+		defer articlesMu.RUnlock()
+
+		if uri == "/api/search" {
+			handleAPISearch(w, r)
 			return
 		}
 
@@ -326,12 +517,12 @@ func makeHTTPServer(srv *server.Server) *http.Server {
 		}
 
 		serve, is404 := srv.FindHandler(uri)
-		if serve != nil {
-			if is404 {
-				if tryServeRedirect(uri) {
-					return
-				}
+		if serve == nil || is404 {
+			if tryServeRedirect(uri) {
+				return
 			}
+		}
+		if serve != nil {
 			serve(w, r)
 			return
 		}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/ntheanh201/blog/tohtml"
+)
+
+// removeDiacritics converts e.g. "Tiếng Việt" => "Tieng Viet"
+func removeDiacritics(s string) string {
+	return tohtml.RemoveDiacritics(s)
+}
+
+// slugify converts s into a string safe to use in urls, transliterating
+// Vietnamese (and other latin) letters with diacritics e.g.
+// "Xin chào, thế giới!" => "xin-chao-the-gioi"
+func slugify(s string) string {
+	return tohtml.Slugify(s)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+	}{
+		{"Hello World", "hello-world"},
+		{"  --Hello,   World!--  ", "hello-world"},
+		{"Đường đến Kubernetes", "duong-den-kubernetes"},
+		{"Tiếng Việt có dấu: ă â ê ô ơ ư", "tieng-viet-co-dau-a-a-e-o-o-u"},
+		{"Những điều ước", "nhung-dieu-uoc"},
+		{"Go 1.18 & generics", "go-1-18-generics"},
+		{"already-a-slug", "already-a-slug"},
+		{"日本語", ""},
+	}
+	for _, test := range tests {
+		got := slugify(test.s)
+		assert.Equal(t, test.exp, got)
+	}
+}
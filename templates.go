@@ -27,7 +27,15 @@ func execTemplateToFile(path string, templateName string, model interface{}) err
 
 func execTemplateToWriter(name string, data interface{}, w io.Writer) error {
 	loadTemplates() // TODO: only reload when changed
-	return templates.ExecuteTemplate(w, name, data)
+	if !liveReloadEnabled {
+		return templates.ExecuteTemplate(w, name, data)
+	}
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	_, err := w.Write(injectLiveReloadScript(buf.Bytes()))
+	return err
 }
 
 func execTemplate(path string, tmplName string, d interface{}, w io.Writer) error {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/kjk/notionapi"
+	"github.com/ntheanh201/blog/tohtml"
+)
+
+// table of contents of an article, built from h1/h2/h3. It's shown
+// in a sidebar if enabled with "toc: true" metadata or if the article
+// is long enough
+
+const (
+	// auto-show table of contents if an article has at least that many
+	// headers and words
+	tocMinHeaders = 3
+	tocMinWords   = 1000
+)
+
+// TOCEntry is a header in table of contents
+type TOCEntry struct {
+	// 1 for h1, 2 for h2, 3 for h3
+	Level  int
+	Title  string
+	Anchor string
+}
+
+func headerLevel(block *notionapi.Block) int {
+	switch block.Type {
+	case notionapi.BlockHeader:
+		return 1
+	case notionapi.BlockSubHeader:
+		return 2
+	}
+	return 3
+}
+
+// buildTOC returns table of contents of the page and a map of no-dash
+// header block id to its readable anchor. Anchors are the same as ids
+// generated by tohtml.Converter with ReadableHeaderIDs
+func buildTOC(page *notionapi.Page) ([]*TOCEntry, map[string]string) {
+	var res []*TOCEntry
+	anchors := tohtml.HeaderIDs(page)
+	for _, block := range tohtml.GetHeaderBlocks(page.Root().Content) {
+		e := &TOCEntry{
+			Level:  headerLevel(block),
+			Title:  notionapi.TextSpansToString(block.InlineContent),
+			Anchor: anchors[notionapi.ToNoDashID(block.ID)],
+		}
+		res = append(res, e)
+	}
+	return res, anchors
+}
+
+// ShowTOC returns true if table of contents should be shown
+func (a *Article) ShowTOC() bool {
+	if len(a.TOC) == 0 {
+		return false
+	}
+	if a.tocOverride != nil {
+		return *a.tocOverride
+	}
+	return len(a.TOC) >= tocMinHeaders && a.WordCount >= tocMinWords
+}
+
+// setTOCOverride parses value of "toc" metadata. Returns false if val
+// is not a bool, so that text like "TOC: see below" isn't metadata
+func (a *Article) setTOCOverride(val string) bool {
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false
+	}
+	a.tocOverride = &b
+	return true
+}
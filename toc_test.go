@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+)
+
+func TestShowTOC(t *testing.T) {
+	toc := []*TOCEntry{
+		{Level: 1, Title: "Giới thiệu", Anchor: "gioi-thieu"},
+		{Level: 2, Title: "Cài đặt", Anchor: "cai-dat"},
+		{Level: 2, Title: "Sử dụng", Anchor: "su-dung"},
+	}
+	a := &Article{TOC: toc, WordCount: 200}
+	assert.False(t, a.ShowTOC())
+	a.WordCount = tocMinWords
+	assert.True(t, a.ShowTOC())
+
+	a.setTOCOverride("false")
+	assert.False(t, a.ShowTOC())
+	a.WordCount = 10
+	a.setTOCOverride("true")
+	assert.True(t, a.ShowTOC())
+
+	// nothing to show
+	a.TOC = nil
+	assert.False(t, a.ShowTOC())
+}
+
+func TestParseTOCMeta(t *testing.T) {
+	textBlock := func(s string) *notionapi.Block {
+		return &notionapi.Block{
+			Type:          notionapi.BlockText,
+			InlineContent: []*notionapi.TextSpan{{Text: s}},
+		}
+	}
+	a := &Article{}
+	assert.True(t, a.maybeParseMeta(0, textBlock("toc: false")))
+	assert.NotNil(t, a.tocOverride)
+	assert.False(t, *a.tocOverride)
+
+	// ordinary body text is not metadata
+	a = &Article{}
+	assert.False(t, a.maybeParseMeta(0, textBlock("TOC: the sections below cover setup and usage")))
+	assert.Nil(t, a.tocOverride)
+}
+
+func TestRewriteURLToHeaderAnchor(t *testing.T) {
+	pageID := "3f2a1b2c3d4e4f5a8b9c0d1e2f3a4b5c"
+	headerID := "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"
+	other := &Article{
+		ID:            pageID,
+		urlOverride:   "/articles/other.html",
+		headerAnchors: map[string]string{"0a1b2c3d4e5f4a6b8c7d9e0f1a2b3c4d": "cai-dat"},
+	}
+	c := &Converter{
+		article: other,
+		idToArticle: func(id string) *Article {
+			if id == pageID {
+				return other
+			}
+			return nil
+		},
+	}
+	assert.Equal(t, "/articles/other.html", c.rewriteURL("https://www.notion.so/Other-"+pageID))
+	assert.Equal(t, "/articles/other.html#cai-dat", c.rewriteURL("https://www.notion.so/Other-"+pageID+"#"+headerID))
+	assert.Equal(t, "#cai-dat", c.rewriteURL("#0a1b2c3d4e5f4a6b8c7d9e0f1a2b3c4d"))
+	// not a header, keep block id
+	assert.Equal(t, "/articles/other.html#aaaa", c.rewriteURL("https://www.notion.so/Other-"+pageID+"#aaaa"))
+}
@@ -0,0 +1,93 @@
+package tohtml
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kjk/notionapi"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// removes diacritics i.e. "ă", "ơ", "ế" => "a", "o", "e"
+var removeMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// Transliterations are letters that don't decompose into a base letter
+// and a mark so RemoveDiacritics replaces them
+var Transliterations = map[string]string{
+	"đ": "d", "Đ": "D",
+	"ø": "o", "Ø": "O",
+	"ł": "l", "Ł": "L",
+	"ß": "ss",
+	"æ": "ae", "Æ": "AE",
+}
+
+var transliterations = newTransliterationsReplacer()
+
+func newTransliterationsReplacer() *strings.Replacer {
+	var pairs []string
+	for from, to := range Transliterations {
+		pairs = append(pairs, from, to)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// RemoveDiacritics converts e.g. "Tiếng Việt" => "Tieng Viet"
+func RemoveDiacritics(s string) string {
+	s = transliterations.Replace(s)
+	res, _, err := transform.String(removeMarks, s)
+	if err != nil {
+		return s
+	}
+	return res
+}
+
+// Slugify converts s into a string safe to use in urls and anchors,
+// transliterating Vietnamese (and other latin) letters with diacritics e.g.
+// "Xin chào, thế giới!" => "xin-chao-the-gioi"
+func Slugify(s string) string {
+	s = strings.ToLower(RemoveDiacritics(s))
+	var sb strings.Builder
+	needsDash := false
+	for _, r := range s {
+		isValid := (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+		if !isValid {
+			needsDash = sb.Len() > 0
+			continue
+		}
+		if needsDash {
+			sb.WriteByte('-')
+			needsDash = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// UniqueAnchor returns s or, if already used, s-2, s-3 etc. and marks
+// it as used
+func UniqueAnchor(s string, used map[string]bool) string {
+	res := s
+	for n := 2; used[res]; n++ {
+		res = s + "-" + strconv.Itoa(n)
+	}
+	used[res] = true
+	return res
+}
+
+// HeaderIDs returns readable, unique ids of h1/h2/h3 blocks of the page,
+// derived from their text. The map is keyed by no-dash block id
+func HeaderIDs(page *notionapi.Page) map[string]string {
+	res := map[string]string{}
+	used := map[string]bool{}
+	for _, block := range GetHeaderBlocks(page.Root().Content) {
+		s := Slugify(notionapi.TextSpansToString(block.InlineContent))
+		if s == "" {
+			s = "section"
+		}
+		res[notionapi.ToNoDashID(block.ID)] = UniqueAnchor(s, used)
+	}
+	return res
+}
@@ -0,0 +1,22 @@
+package tohtml
+
+import (
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "xin-chao-the-gioi", Slugify("Xin chào, thế giới!"))
+	assert.Equal(t, "duong-di", Slugify("Đường đi"))
+	assert.Equal(t, "go-1-18-generics", Slugify("Go 1.18: generics"))
+	assert.Equal(t, "", Slugify("你好"))
+}
+
+func TestUniqueAnchor(t *testing.T) {
+	used := map[string]bool{}
+	assert.Equal(t, "intro", UniqueAnchor("intro", used))
+	assert.Equal(t, "intro-2", UniqueAnchor("intro", used))
+	assert.Equal(t, "intro-3", UniqueAnchor("intro", used))
+	assert.Equal(t, "setup", UniqueAnchor("setup", used))
+}
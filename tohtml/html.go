@@ -247,6 +247,10 @@ type Converter struct {
 	// Tested with katex 0.10.2
 	UseKatexToRenderEquation bool
 
+	// if true, block and inline equations are rendered as MathML with
+	// TeXToMathML. UseKatexToRenderEquation takes precedence
+	RenderMathML bool
+
 	// If UseKatexToRenderEquation is true, you can provide path to katex binary
 	// here. Otherwise we'll try to locate it using exec.LookPath()
 	// If UseKatexToRenderEquation is true but we can't locate katex binary
@@ -257,6 +261,15 @@ type Converter struct {
 	// to h1/h2/h3
 	AddHeaderAnchor bool
 
+	// if true, h1/h2/h3 get readable ids derived from their text (see
+	// HeaderIDs) instead of block id. Block id is kept as an alias anchor
+	// so that old links still work
+	ReadableHeaderIDs bool
+
+	// HeaderID allows over-riding id of h1/h2/h3 (used in anchors and
+	// table of contents). Return "" to use the default
+	HeaderID func(block *notionapi.Block) string
+
 	// allows over-riding rendering of specific blocks
 	// return false for default rendering
 	RenderBlockOverride BlockRenderFunc
@@ -284,6 +297,7 @@ type Converter struct {
 	Data interface{}
 
 	didImportKatexCSS bool
+	headerIDs         map[string]string
 	bufs              []*bytes.Buffer
 	indent            int
 }
@@ -477,11 +491,30 @@ func (c *Converter) RenderInline(b *notionapi.TextSpan) {
 			date := notionapi.AttrGetDate(attr)
 			start += c.FormatDate(date)
 			text = ""
+		case attrEquation:
+			start += c.inlineEquation(attr)
+			text = ""
 		}
 	}
 	c.NoIndentPrintf(start + EscapeHTML(text) + end)
 }
 
+// inline equation is "⁍" text with ["e", "${tex}"] attribute
+const attrEquation = "e"
+
+func (c *Converter) inlineEquation(attr notionapi.TextAttr) string {
+	if len(attr) < 2 {
+		return ""
+	}
+	tex := attr[1]
+	if c.RenderMathML {
+		if mathml, err := TeXToMathML(tex, false); err == nil {
+			return mathml
+		}
+	}
+	return `<code class="equation">` + EscapeHTML(tex) + `</code>`
+}
+
 // RenderInlines renders inline blocks
 func (c *Converter) RenderInlines(blocks []*notionapi.TextSpan) {
 	for _, block := range blocks {
@@ -806,6 +839,15 @@ func (c *Converter) RenderEquation(block *notionapi.Block) {
 	c.indent++
 	defer c.decIndent()
 
+	if !c.UseKatexToRenderEquation && c.RenderMathML {
+		tex := notionapi.TextSpansToString(block.InlineContent)
+		if mathml, err := TeXToMathML(tex, true); err == nil {
+			c.Printf(`<figure id="%s" class="equation">`, block.ID)
+			c.Printf(`%s`, mathml)
+			c.Printf(`</figure>`)
+			return
+		}
+	}
 	if !c.UseKatexToRenderEquation {
 		c.Printf(`<figure id="%s" class="equation">`, block.ID)
 		c.RenderInlines(block.InlineContent)
@@ -909,14 +951,35 @@ func (c *Converter) RenderHeaderLevel(block *notionapi.Block, level int) {
 	defer c.decIndent()
 
 	cls := GetBlockColorClass(block)
-	c.Printf(`<h%d id="%s" class="%s">`, level, block.ID, cls)
+	id := c.headerID(block)
+	c.Printf(`<h%d id="%s" class="%s">`, level, id, cls)
+	if id != block.ID {
+		c.Printf(`<a id="%s" class="header-alias"></a>`, block.ID)
+	}
 	c.RenderInlines(block.InlineContent)
 	if c.AddHeaderAnchor {
-		c.Printf(`<a class="header-anchor" href="#%s" aria-hidden="true"><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><path d="M5.88.03c-.18.01-.36.03-.53.09-.27.1-.53.25-.75.47a.5.5 0 1 0 .69.69c.11-.11.24-.17.38-.22.35-.12.78-.07 1.06.22.39.39.39 1.04 0 1.44l-1.5 1.5c-.44.44-.8.48-1.06.47-.26-.01-.41-.13-.41-.13a.5.5 0 1 0-.5.88s.34.22.84.25c.5.03 1.2-.16 1.81-.78l1.5-1.5c.78-.78.78-2.04 0-2.81-.28-.28-.61-.45-.97-.53-.18-.04-.38-.04-.56-.03zm-2 2.31c-.5-.02-1.19.15-1.78.75l-1.5 1.5c-.78.78-.78 2.04 0 2.81.56.56 1.36.72 2.06.47.27-.1.53-.25.75-.47a.5.5 0 1 0-.69-.69c-.11.11-.24.17-.38.22-.35.12-.78.07-1.06-.22-.39-.39-.39-1.04 0-1.44l1.5-1.5c.4-.4.75-.45 1.03-.44.28.01.47.09.47.09a.5.5 0 1 0 .44-.88s-.34-.2-.84-.22z"></path></svg></a>`, block.ID)
+		c.Printf(`<a class="header-anchor" href="#%s" aria-hidden="true"><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><path d="M5.88.03c-.18.01-.36.03-.53.09-.27.1-.53.25-.75.47a.5.5 0 1 0 .69.69c.11-.11.24-.17.38-.22.35-.12.78-.07 1.06.22.39.39.39 1.04 0 1.44l-1.5 1.5c-.44.44-.8.48-1.06.47-.26-.01-.41-.13-.41-.13a.5.5 0 1 0-.5.88s.34.22.84.25c.5.03 1.2-.16 1.81-.78l1.5-1.5c.78-.78.78-2.04 0-2.81-.28-.28-.61-.45-.97-.53-.18-.04-.38-.04-.56-.03zm-2 2.31c-.5-.02-1.19.15-1.78.75l-1.5 1.5c-.78.78-.78 2.04 0 2.81.56.56 1.36.72 2.06.47.27-.1.53-.25.75-.47a.5.5 0 1 0-.69-.69c-.11.11-.24.17-.38.22-.35.12-.78.07-1.06-.22-.39-.39-.39-1.04 0-1.44l1.5-1.5c.4-.4.75-.45 1.03-.44.28.01.47.09.47.09a.5.5 0 1 0 .44-.88s-.34-.2-.84-.22z"></path></svg></a>`, id)
 	}
 	c.Printf(`</h%d>`, level)
 }
 
+func (c *Converter) headerID(block *notionapi.Block) string {
+	if c.HeaderID != nil {
+		if id := c.HeaderID(block); id != "" {
+			return id
+		}
+	}
+	if c.ReadableHeaderIDs && !c.NotionCompat {
+		if c.headerIDs == nil {
+			c.headerIDs = HeaderIDs(c.Page)
+		}
+		if id := c.headerIDs[notionapi.ToNoDashID(block.ID)]; id != "" {
+			return id
+		}
+	}
+	return block.ID
+}
+
 // RenderHeader renders BlockHeader
 func (c *Converter) RenderHeader(block *notionapi.Block) {
 	c.RenderHeaderLevel(block, 1)
@@ -1026,6 +1089,11 @@ func isHeaderBlock(block *notionapi.Block) bool {
 	return false
 }
 
+// GetHeaderBlocks returns h1/h2/h3 blocks, in order of appearance
+func GetHeaderBlocks(blocks []*notionapi.Block) []*notionapi.Block {
+	return getHeaderBlocks(blocks, map[string]bool{})
+}
+
 func getHeaderBlocks(blocks []*notionapi.Block, seen map[string]bool) []*notionapi.Block {
 	var res []*notionapi.Block
 	for i, b := range blocks {
@@ -1096,7 +1164,7 @@ func (c *Converter) RenderTableOfContents(block *notionapi.Block) {
 		s := c.GetInlineContent(b.InlineContent)
 		c.Printf(`<p class="table_of_contents-item table_of_contents-indent-%d">`, indent)
 		{
-			c.Printf(`<a class="table_of_contents-link" href="#%s">%s</a>`, b.ID, s)
+			c.Printf(`<a class="table_of_contents-link" href="#%s">%s</a>`, c.headerID(b), s)
 		}
 		c.Printf(`</p>`)
 	}
@@ -0,0 +1,714 @@
+package tohtml
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// converts LaTeX math (the subset used in Notion equations) to MathML,
+// which browsers render natively, without katex binary, fonts or css.
+// Unknown commands are rendered as <merror>
+
+var texGreek = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+	"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+}
+
+// symbols rendered as <mi>
+var texIdentifiers = map[string]string{
+	"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅", "varnothing": "∅",
+	"hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ", "aleph": "ℵ", "wp": "℘",
+	"imath": "ı", "jmath": "ȷ",
+}
+
+// symbols rendered as <mo>
+var texOperators = map[string]string{
+	"times": "×", "cdot": "⋅", "pm": "±", "mp": "∓", "div": "÷", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "ominus": "⊖",
+	"otimes": "⊗", "odot": "⊙", "setminus": "∖", "cup": "∪", "cap": "∩",
+	"wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"le": "≤", "leq": "≤", "ge": "≥", "geq": "≥", "ne": "≠", "neq": "≠",
+	"ll": "≪", "gg": "≫", "approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃",
+	"cong": "≅", "propto": "∝", "prec": "≺", "succ": "≻", "preceq": "⪯", "succeq": "⪰",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆",
+	"supset": "⊃", "supseteq": "⊇", "forall": "∀", "exists": "∃", "nexists": "∄",
+	"perp": "⊥", "parallel": "∥", "mid": "∣", "angle": "∠", "triangle": "△",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←",
+	"leftrightarrow": "↔", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"Leftrightarrow": "⇔", "implies": "⟹", "impliedby": "⟸", "iff": "⟺",
+	"mapsto": "↦", "longrightarrow": "⟶", "longleftarrow": "⟵",
+	"uparrow": "↑", "downarrow": "↓", "nearrow": "↗", "searrow": "↘",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"prime": "′", "colon": ":", "vert": "|", "Vert": "‖", "|": "‖",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "{": "{", "}": "}", "lbrace": "{", "rbrace": "}",
+	"#": "#", "%": "%", "$": "$", "&": "&", "_": "_",
+}
+
+// operators with limits (under and over in display mode)
+var texBigOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂",
+	"bigoplus": "⨁", "bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+}
+
+// operators with scripts on the side
+var texIntegrals = map[string]string{
+	"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+}
+
+var texFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true,
+	"tanh": true, "coth": true, "log": true, "ln": true, "lg": true, "exp": true,
+	"deg": true, "dim": true, "ker": true, "arg": true, "hom": true, "bmod": true,
+}
+
+// functions with limits e.g. \lim_{x \to 0}
+var texLimitFunctions = map[string]bool{
+	"lim": true, "liminf": true, "limsup": true, "max": true, "min": true,
+	"sup": true, "inf": true, "det": true, "gcd": true, "Pr": true, "argmax": true,
+	"argmin": true,
+}
+
+var texFonts = map[string]string{
+	"mathbf": "bold", "mathit": "italic", "mathrm": "normal", "mathbb": "double-struck",
+	"mathcal": "script", "mathscr": "script", "mathfrak": "fraktur",
+	"mathsf": "sans-serif", "mathtt": "monospace", "boldsymbol": "bold-italic",
+	"bm": "bold-italic",
+}
+
+var texAccents = map[string]string{
+	"hat": "^", "widehat": "^", "bar": "¯", "overline": "¯", "vec": "→",
+	"overrightarrow": "→", "overleftarrow": "←", "dot": "˙", "ddot": "¨",
+	"tilde": "~", "widetilde": "~", "check": "ˇ", "breve": "˘", "acute": "´",
+	"grave": "`",
+}
+
+var texSpaces = map[string]string{
+	",": "0.1667em", "thinspace": "0.1667em", ":": "0.2222em", ">": "0.2222em",
+	"medspace": "0.2222em", ";": "0.2778em", "thickspace": "0.2778em",
+	" ": "0.25em", "quad": "1em", "qquad": "2em", "!": "-0.1667em",
+}
+
+var texBigDelims = map[string]string{
+	"big": "1.2em", "bigl": "1.2em", "bigr": "1.2em", "bigm": "1.2em",
+	"Big": "1.8em", "Bigl": "1.8em", "Bigr": "1.8em", "Bigm": "1.8em",
+	"bigg": "2.4em", "biggl": "2.4em", "biggr": "2.4em", "biggm": "2.4em",
+	"Bigg": "3em", "Biggl": "3em", "Biggr": "3em", "Biggm": "3em",
+}
+
+// commands whose argument is text, not math
+var texTextCommands = map[string]bool{
+	"text": true, "textrm": true, "textit": true, "textbf": true, "mbox": true,
+	"operatorname": true, "operatorname*": true,
+}
+
+type texToken struct {
+	s string
+	// if true, s is the raw text argument of a command like \text
+	isText bool
+}
+
+func isTeXLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func tokenizeTeX(s string) ([]texToken, error) {
+	var res []texToken
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\\':
+			j := i + 1
+			for j < len(rs) && isTeXLetter(rs[j]) {
+				j++
+			}
+			if j == i+1 && j < len(rs) {
+				// one character commands like \, or \{
+				j++
+			}
+			if j < len(rs) && rs[j] == '*' && string(rs[i+1:j]) == "operatorname" {
+				j++
+			}
+			cmd := string(rs[i:j])
+			res = append(res, texToken{s: cmd})
+			i = j
+			if !texTextCommands[cmd[1:]] {
+				continue
+			}
+			// \text{...} keeps spaces, so we tokenize it here
+			for i < len(rs) && unicode.IsSpace(rs[i]) {
+				i++
+			}
+			if i >= len(rs) || rs[i] != '{' {
+				return nil, fmt.Errorf("expected '{' after %s", cmd)
+			}
+			depth := 0
+			start := i + 1
+			for ; i < len(rs); i++ {
+				if rs[i] == '{' {
+					depth++
+				} else if rs[i] == '}' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if i >= len(rs) {
+				return nil, fmt.Errorf("missing '}' after %s", cmd)
+			}
+			res = append(res, texToken{s: string(rs[start:i]), isText: true})
+			i++
+		case r >= '0' && r <= '9':
+			j := i
+			for j < len(rs) && ((rs[j] >= '0' && rs[j] <= '9') || (rs[j] == '.' && j+1 < len(rs) && rs[j+1] >= '0' && rs[j+1] <= '9')) {
+				j++
+			}
+			res = append(res, texToken{s: string(rs[i:j])})
+			i = j
+		default:
+			res = append(res, texToken{s: string(r)})
+			i++
+		}
+	}
+	return res, nil
+}
+
+// mathNode is MathML of a single element
+type mathNode struct {
+	xml string
+	// if true, sub and super scripts go under and over
+	limits bool
+	// \displaystyle and \textstyle apply to the rest of the group
+	style string
+}
+
+type texParser struct {
+	toks []texToken
+	pos  int
+	// mathvariant for identifiers, set by e.g. \mathbf
+	variant string
+}
+
+func (p *texParser) peek() string {
+	if p.pos >= len(p.toks) || p.toks[p.pos].isText {
+		return ""
+	}
+	return p.toks[p.pos].s
+}
+
+func (p *texParser) atEnd() bool {
+	return p.pos >= len(p.toks)
+}
+
+func (p *texParser) next() texToken {
+	t := p.toks[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *texParser) expect(s string) error {
+	if p.atEnd() || p.peek() != s {
+		return fmt.Errorf("expected '%s'", s)
+	}
+	p.pos++
+	return nil
+}
+
+func mrow(nodes []string) string {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return "<mrow>" + strings.Join(nodes, "") + "</mrow>"
+}
+
+func mathElem(tag string, attrs string, content string) string {
+	return "<" + tag + attrs + ">" + content + "</" + tag + ">"
+}
+
+func mo(s string) string {
+	return mathElem("mo", "", html.EscapeString(s))
+}
+
+func (p *texParser) mi(s string) string {
+	attrs := ""
+	if p.variant != "" {
+		attrs = fmt.Sprintf(` mathvariant="%s"`, p.variant)
+	}
+	return mathElem("mi", attrs, html.EscapeString(s))
+}
+
+func isStop(s string, stop []string) bool {
+	for _, st := range stop {
+		if s == st {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSeq parses until one of stop tokens (which is not consumed) or
+// the end
+func (p *texParser) parseSeq(stop ...string) ([]string, error) {
+	var nodes []mathNode
+	for !p.atEnd() {
+		t := p.peek()
+		if isStop(t, stop) {
+			break
+		}
+		if t == "}" {
+			return nil, fmt.Errorf("unexpected '}'")
+		}
+		if t == "^" || t == "_" || t == "'" {
+			base := mathNode{xml: "<mrow></mrow>"}
+			if len(nodes) > 0 {
+				base = nodes[len(nodes)-1]
+				nodes = nodes[:len(nodes)-1]
+			}
+			n, err := p.parseScripts(base)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+			continue
+		}
+		n, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		if n.style != "" {
+			rest, err := p.parseSeq(stop...)
+			if err != nil {
+				return nil, err
+			}
+			attrs := fmt.Sprintf(` displaystyle="%s"`, n.style)
+			n = mathNode{xml: mathElem("mstyle", attrs, strings.Join(rest, ""))}
+		}
+		if n.xml != "" {
+			nodes = append(nodes, n)
+		}
+	}
+	var res []string
+	for _, n := range nodes {
+		res = append(res, n.xml)
+	}
+	return res, nil
+}
+
+func (p *texParser) parseScripts(base mathNode) (mathNode, error) {
+	var sub, sup, primes string
+	for !p.atEnd() {
+		t := p.peek()
+		if t == "'" {
+			p.pos++
+			primes += "′"
+			continue
+		}
+		if t != "^" && t != "_" {
+			break
+		}
+		p.pos++
+		arg, err := p.parseArg()
+		if err != nil {
+			return base, err
+		}
+		if t == "^" {
+			sup = arg
+		} else {
+			sub = arg
+		}
+	}
+	if primes != "" {
+		sup = mrow([]string{mo(primes), sup})
+	}
+	under, over := "msub", "msup"
+	both := "msubsup"
+	if base.limits {
+		under, over, both = "munder", "mover", "munderover"
+	}
+	switch {
+	case sub != "" && sup != "":
+		return mathNode{xml: mathElem(both, "", base.xml+sub+sup)}, nil
+	case sub != "":
+		return mathNode{xml: mathElem(under, "", base.xml+sub)}, nil
+	}
+	return mathNode{xml: mathElem(over, "", base.xml+sup)}, nil
+}
+
+// parseArg parses an argument of a command or a script: a group in
+// braces or a single token
+func (p *texParser) parseArg() (string, error) {
+	if p.atEnd() {
+		return "", fmt.Errorf("missing argument")
+	}
+	n, err := p.parseAtom()
+	if err != nil {
+		return "", err
+	}
+	if n.xml == "" {
+		return "<mrow></mrow>", nil
+	}
+	return n.xml, nil
+}
+
+// parseDelim parses delimiter after \left, \right, \big etc.
+func (p *texParser) parseDelim() (string, error) {
+	if p.atEnd() {
+		return "", fmt.Errorf("missing delimiter")
+	}
+	t := p.next().s
+	if t == "." {
+		return "", nil
+	}
+	if strings.HasPrefix(t, `\`) {
+		s, ok := texOperators[t[1:]]
+		if !ok {
+			return "", fmt.Errorf("invalid delimiter '%s'", t)
+		}
+		return s, nil
+	}
+	return t, nil
+}
+
+// parseEnvName parses {name} after \begin or \end
+func (p *texParser) parseEnvName() (string, error) {
+	if err := p.expect("{"); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for !p.atEnd() && p.peek() != "}" {
+		sb.WriteString(p.next().s)
+	}
+	if err := p.expect("}"); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (p *texParser) parseEnv() (mathNode, error) {
+	name, err := p.parseEnvName()
+	if err != nil {
+		return mathNode{}, err
+	}
+	if name == "array" {
+		// skip column spec
+		if _, err = p.parseArg(); err != nil {
+			return mathNode{}, err
+		}
+	}
+	var rows [][]string
+	var row []string
+	for {
+		cell, err := p.parseSeq("&", `\\`, `\end`)
+		if err != nil {
+			return mathNode{}, err
+		}
+		row = append(row, mathElem("mtd", "", strings.Join(cell, "")))
+		if p.atEnd() {
+			return mathNode{}, fmt.Errorf("missing \\end{%s}", name)
+		}
+		t := p.next().s
+		if t == "&" {
+			continue
+		}
+		rows = append(rows, row)
+		row = nil
+		if t == `\end` {
+			break
+		}
+	}
+	endName, err := p.parseEnvName()
+	if err != nil {
+		return mathNode{}, err
+	}
+	if endName != name {
+		return mathNode{}, fmt.Errorf("\\begin{%s} ended with \\end{%s}", name, endName)
+	}
+	// a trailing \\ creates an empty row
+	if n := len(rows); n > 1 && len(rows[n-1]) == 1 && rows[n-1][0] == "<mtd></mtd>" {
+		rows = rows[:n-1]
+	}
+	var sb strings.Builder
+	for _, row := range rows {
+		sb.WriteString(mathElem("mtr", "", strings.Join(row, "")))
+	}
+	attrs := ""
+	switch strings.TrimSuffix(name, "*") {
+	case "cases":
+		attrs = ` columnalign="left left"`
+	case "aligned", "align", "split":
+		attrs = ` columnalign="right left" columnspacing="0em" displaystyle="true"`
+	case "gathered", "gather":
+		attrs = ` displaystyle="true"`
+	}
+	table := mathElem("mtable", attrs, sb.String())
+	fences := map[string][2]string{
+		"pmatrix": {"(", ")"}, "bmatrix": {"[", "]"}, "Bmatrix": {"{", "}"},
+		"vmatrix": {"|", "|"}, "Vmatrix": {"‖", "‖"}, "cases": {"{", ""},
+	}
+	f, ok := fences[name]
+	if !ok {
+		return mathNode{xml: table}, nil
+	}
+	nodes := []string{fence(f[0]), table, fence(f[1])}
+	return mathNode{xml: mrow(nodes)}, nil
+}
+
+func fence(s string) string {
+	if s == "" {
+		return ""
+	}
+	return mathElem("mo", ` fence="true" stretchy="true"`, html.EscapeString(s))
+}
+
+func (p *texParser) parseAtom() (mathNode, error) {
+	if p.atEnd() {
+		return mathNode{}, fmt.Errorf("unexpected end of input")
+	}
+	tok := p.next()
+	t := tok.s
+	if tok.isText {
+		return mathNode{}, fmt.Errorf("unexpected text '%s'", t)
+	}
+	switch {
+	case t == "{":
+		nodes, err := p.parseSeq("}")
+		if err != nil {
+			return mathNode{}, err
+		}
+		if err = p.expect("}"); err != nil {
+			return mathNode{}, err
+		}
+		return mathNode{xml: mathElem("mrow", "", strings.Join(nodes, ""))}, nil
+	case t[0] >= '0' && t[0] <= '9':
+		return mathNode{xml: mathElem("mn", "", t)}, nil
+	case strings.HasPrefix(t, `\`) && len(t) > 1:
+		return p.parseCommand(t[1:])
+	case unicode.IsLetter([]rune(t)[0]):
+		return mathNode{xml: p.mi(t)}, nil
+	case t == "&":
+		// outside of a table
+		return mathNode{}, nil
+	case t == "~":
+		return mathNode{xml: `<mspace width="0.25em"></mspace>`}, nil
+	case t == "-":
+		return mathNode{xml: mo("−")}, nil
+	case t == "*":
+		return mathNode{xml: mo("∗")}, nil
+	}
+	return mathNode{xml: mo(t)}, nil
+}
+
+func (p *texParser) parseCommand(cmd string) (mathNode, error) {
+	if s, ok := texGreek[cmd]; ok {
+		if unicode.IsUpper([]rune(cmd)[0]) && p.variant == "" {
+			return mathNode{xml: mathElem("mi", ` mathvariant="normal"`, s)}, nil
+		}
+		return mathNode{xml: p.mi(s)}, nil
+	}
+	if s, ok := texIdentifiers[cmd]; ok {
+		return mathNode{xml: p.mi(s)}, nil
+	}
+	if s, ok := texOperators[cmd]; ok {
+		return mathNode{xml: mo(s)}, nil
+	}
+	if s, ok := texBigOperators[cmd]; ok {
+		return mathNode{xml: mathElem("mo", ` largeop="true" movablelimits="true"`, s), limits: true}, nil
+	}
+	if s, ok := texIntegrals[cmd]; ok {
+		return mathNode{xml: mathElem("mo", ` largeop="true"`, s)}, nil
+	}
+	if texFunctions[cmd] {
+		return mathNode{xml: mathElem("mi", "", cmd)}, nil
+	}
+	if texLimitFunctions[cmd] {
+		name := cmd
+		if cmd == "argmax" || cmd == "argmin" {
+			name = "arg " + cmd[3:]
+		}
+		return mathNode{xml: mathElem("mo", ` movablelimits="true"`, name), limits: true}, nil
+	}
+	if width, ok := texSpaces[cmd]; ok {
+		return mathNode{xml: fmt.Sprintf(`<mspace width="%s"></mspace>`, width)}, nil
+	}
+	if variant, ok := texFonts[cmd]; ok {
+		prev := p.variant
+		p.variant = variant
+		arg, err := p.parseArg()
+		p.variant = prev
+		return mathNode{xml: arg}, err
+	}
+	if accent, ok := texAccents[cmd]; ok {
+		arg, err := p.parseArg()
+		stretchy := "false"
+		if strings.HasPrefix(cmd, "wide") || strings.HasPrefix(cmd, "over") {
+			stretchy = "true"
+		}
+		accentMo := mathElem("mo", fmt.Sprintf(` stretchy="%s"`, stretchy), html.EscapeString(accent))
+		return mathNode{xml: mathElem("mover", ` accent="true"`, arg+accentMo)}, err
+	}
+	if size, ok := texBigDelims[cmd]; ok {
+		d, err := p.parseDelim()
+		attrs := fmt.Sprintf(` minsize="%s" maxsize="%s" stretchy="true" symmetric="true"`, size, size)
+		return mathNode{xml: mathElem("mo", attrs, html.EscapeString(d))}, err
+	}
+	if texTextCommands[cmd] {
+		if p.atEnd() || !p.toks[p.pos].isText {
+			return mathNode{}, fmt.Errorf("missing argument of \\%s", cmd)
+		}
+		text := html.EscapeString(p.next().s)
+		switch cmd {
+		case "operatorname":
+			return mathNode{xml: mathElem("mi", "", text)}, nil
+		case "operatorname*":
+			return mathNode{xml: mathElem("mo", ` movablelimits="true"`, text), limits: true}, nil
+		case "textit":
+			return mathNode{xml: mathElem("mtext", ` mathvariant="italic"`, text)}, nil
+		case "textbf":
+			return mathNode{xml: mathElem("mtext", ` mathvariant="bold"`, text)}, nil
+		}
+		return mathNode{xml: mathElem("mtext", "", text)}, nil
+	}
+
+	switch cmd {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num, err := p.parseArg()
+		if err != nil {
+			return mathNode{}, err
+		}
+		den, err := p.parseArg()
+		if err != nil {
+			return mathNode{}, err
+		}
+		frac := mathElem("mfrac", "", num+den)
+		switch cmd {
+		case "dfrac", "cfrac":
+			frac = mathElem("mstyle", ` displaystyle="true"`, frac)
+		case "tfrac":
+			frac = mathElem("mstyle", ` displaystyle="false"`, frac)
+		}
+		return mathNode{xml: frac}, nil
+	case "binom":
+		n, err := p.parseArg()
+		if err != nil {
+			return mathNode{}, err
+		}
+		k, err := p.parseArg()
+		if err != nil {
+			return mathNode{}, err
+		}
+		frac := mathElem("mfrac", ` linethickness="0"`, n+k)
+		return mathNode{xml: mrow([]string{fence("("), frac, fence(")")})}, nil
+	case "sqrt":
+		var index []string
+		if p.peek() == "[" {
+			p.pos++
+			var err error
+			if index, err = p.parseSeq("]"); err != nil {
+				return mathNode{}, err
+			}
+			if err = p.expect("]"); err != nil {
+				return mathNode{}, err
+			}
+		}
+		arg, err := p.parseArg()
+		if err != nil {
+			return mathNode{}, err
+		}
+		if index != nil {
+			return mathNode{xml: mathElem("mroot", "", arg+mrow(index))}, nil
+		}
+		return mathNode{xml: mathElem("msqrt", "", arg)}, nil
+	case "underline":
+		arg, err := p.parseArg()
+		return mathNode{xml: mathElem("munder", ` accentunder="true"`, arg+mathElem("mo", ` stretchy="true"`, "_"))}, err
+	case "overbrace", "underbrace":
+		arg, err := p.parseArg()
+		if cmd == "overbrace" {
+			return mathNode{xml: mathElem("mover", "", arg+mathElem("mo", ` stretchy="true"`, "⏞")), limits: true}, err
+		}
+		return mathNode{xml: mathElem("munder", "", arg+mathElem("mo", ` stretchy="true"`, "⏟")), limits: true}, err
+	case "left":
+		open, err := p.parseDelim()
+		if err != nil {
+			return mathNode{}, err
+		}
+		inner, err := p.parseSeq(`\right`)
+		if err != nil {
+			return mathNode{}, err
+		}
+		if err = p.expect(`\right`); err != nil {
+			return mathNode{}, err
+		}
+		closing, err := p.parseDelim()
+		if err != nil {
+			return mathNode{}, err
+		}
+		nodes := append([]string{fence(open)}, inner...)
+		nodes = append(nodes, fence(closing))
+		return mathNode{xml: mathElem("mrow", "", strings.Join(nodes, ""))}, nil
+	case "middle":
+		d, err := p.parseDelim()
+		return mathNode{xml: mathElem("mo", ` stretchy="true"`, html.EscapeString(d))}, err
+	case "right":
+		return mathNode{}, fmt.Errorf("\\right without \\left")
+	case "begin":
+		return p.parseEnv()
+	case "end":
+		return mathNode{}, fmt.Errorf("\\end without \\begin")
+	case "not":
+		n, err := p.parseAtom()
+		if err != nil {
+			return n, err
+		}
+		// combining long solidus overlay e.g. \not= is ≠
+		n.xml = strings.Replace(n.xml, "</mo>", "̸</mo>", 1)
+		return n, nil
+	case "displaystyle":
+		return mathNode{style: "true"}, nil
+	case "textstyle":
+		return mathNode{style: "false"}, nil
+	case `\`:
+		// new line outside of a table
+		return mathNode{xml: `<mspace linebreak="newline"></mspace>`}, nil
+	case "limits", "nolimits", "nonumber", "notag":
+		return mathNode{}, nil
+	}
+	s := html.EscapeString(`\` + cmd)
+	return mathNode{xml: mathElem("merror", "", mathElem("mtext", "", s))}, nil
+}
+
+// TeXToMathML converts LaTeX math to MathML <math> element. If display is
+// true, the equation is rendered as a block. Returns an error if tex
+// can't be parsed e.g. has unbalanced braces
+func TeXToMathML(tex string, display bool) (string, error) {
+	toks, err := tokenizeTeX(tex)
+	if err != nil {
+		return "", err
+	}
+	p := &texParser{toks: toks}
+	nodes, err := p.parseSeq()
+	if err != nil {
+		return "", err
+	}
+	attrs := ""
+	if display {
+		attrs = ` display="block"`
+	}
+	// tex source allows copying the equation and is used by screen readers
+	annotation := mathElem("annotation", ` encoding="application/x-tex"`, html.EscapeString(strings.TrimSpace(tex)))
+	content := mathElem("semantics", "", mathElem("mrow", "", strings.Join(nodes, ""))+annotation)
+	return mathElem("math", ` xmlns="http://www.w3.org/1998/Math/MathML"`+attrs, content), nil
+}
@@ -0,0 +1,56 @@
+package tohtml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func texToMathMLBody(t *testing.T, tex string) string {
+	s, err := TeXToMathML(tex, false)
+	assert.NoError(t, err)
+	start := strings.Index(s, "<semantics><mrow>") + len("<semantics><mrow>")
+	end := strings.Index(s, "</mrow><annotation")
+	return s[start:end]
+}
+
+func TestTeXToMathML(t *testing.T) {
+	tests := []struct {
+		tex  string
+		want string
+	}{
+		{`x^2`, `<msup><mi>x</mi><mn>2</mn></msup>`},
+		{`a_{i}^{n}`, `<msubsup><mi>a</mi><mrow><mi>i</mi></mrow><mrow><mi>n</mi></mrow></msubsup>`},
+		{`f'`, `<msup><mi>f</mi><mrow><mo>′</mo></mrow></msup>`},
+		{`\frac{1}{2}`, `<mfrac><mrow><mn>1</mn></mrow><mrow><mn>2</mn></mrow></mfrac>`},
+		{`\sqrt[3]{x}`, `<mroot><mrow><mi>x</mi></mrow><mn>3</mn></mroot>`},
+		{`\alpha \leq \Omega`, `<mi>α</mi><mo>≤</mo><mi mathvariant="normal">Ω</mi>`},
+		{`a - b < c`, `<mi>a</mi><mo>−</mo><mi>b</mi><mo>&lt;</mo><mi>c</mi>`},
+		{`\sum_{i=1}^n i`, `<munderover><mo largeop="true" movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><mi>i</mi>`},
+		{`\sin x`, `<mi>sin</mi><mi>x</mi>`},
+		{`\text{if } x`, `<mtext>if </mtext><mi>x</mi>`},
+		{`\mathbb{R}`, `<mrow><mi mathvariant="double-struck">R</mi></mrow>`},
+		{`\left( x \right)`, `<mrow><mo fence="true" stretchy="true">(</mo><mi>x</mi><mo fence="true" stretchy="true">)</mo></mrow>`},
+		{`\begin{pmatrix} 1 & 2 \\ 3 & 4 \end{pmatrix}`, `<mrow><mo fence="true" stretchy="true">(</mo><mtable><mtr><mtd><mn>1</mn></mtd><mtd><mn>2</mn></mtd></mtr><mtr><mtd><mn>3</mn></mtd><mtd><mn>4</mn></mtd></mtr></mtable><mo fence="true" stretchy="true">)</mo></mrow>`},
+		{`\hat{x}`, `<mover accent="true"><mrow><mi>x</mi></mrow><mo stretchy="false">^</mo></mover>`},
+		{`\foo`, `<merror><mtext>\foo</mtext></merror>`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, texToMathMLBody(t, test.tex), test.tex)
+	}
+}
+
+func TestTeXToMathMLDisplay(t *testing.T) {
+	s, err := TeXToMathML(`E = mc^2`, true)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(s, `<math xmlns="http://www.w3.org/1998/Math/MathML" display="block">`))
+	assert.True(t, strings.Contains(s, `<annotation encoding="application/x-tex">E = mc^2</annotation>`))
+}
+
+func TestTeXToMathMLErrors(t *testing.T) {
+	for _, tex := range []string{`\frac{1}{2`, `x}`, `\left( x`, `\begin{matrix} 1 \end{pmatrix}`, `x^`, `\text x`, `a \not`, `\not`} {
+		_, err := TeXToMathML(tex, false)
+		assert.Error(t, err, tex)
+	}
+}
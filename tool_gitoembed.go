@@ -21,8 +21,6 @@ Notes about oembed standard:
 
 var (
 	oembedDownloadCache = NewHTTPDownloadCache()
-	//baseURL             = "https://www.onlinetool.io"
-	baseURL = "https://blog.kowalczyk.info"
 )
 
 // json oembed response
@@ -36,6 +34,9 @@ type oembedResult struct {
 	Width        interface{} `json:"width"`
 	Title        string      `json:"title"`
 	HTML         string      `json:"html"`
+	AuthorName   string      `json:"author_name,omitempty"`
+	AuthorURL    string      `json:"author_url,omitempty"`
+	ThumbnailURL string      `json:"thumbnail_url,omitempty"`
 }
 
 /*
@@ -148,6 +149,7 @@ func handleGitOembedWidget(w http.ResponseWriter, r *http.Request) {
 		query += "&theme" + args.theme
 	}
 	fileName := getFileNameFromURL(githubURL)
+	baseURL := getGitOembedBaseURL()
 
 	timeStart := time.Now()
 	d, fromCache, err := oembedDownloadCache.Download(rawURL)
@@ -206,6 +208,7 @@ func handleGitOembedOembed(w http.ResponseWriter, r *http.Request) {
 	if strings.EqualFold(args.format, "xml") {
 		format = "xml"
 	}
+	baseURL := getGitOembedBaseURL()
 	widgetURL := baseURL + "/gitoembed/widget?url=" + gitHubURL
 	if args.noLines {
 		widgetURL += "&nolines"
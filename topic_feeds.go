@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+)
+
+// atom feeds for a single tag, series or collection
+
+type topicFeed struct {
+	// e.g. /tag/go/atom.xml
+	URL   string
+	Title string
+	// newest first
+	articles []*Article
+}
+
+// topicFeedURL returns url of atom feed for a page e.g.
+// "/tag/go.html" => "/tag/go/atom.xml"
+func topicFeedURL(pageURL string) string {
+	return strings.TrimSuffix(pageURL, ".html") + "/atom.xml"
+}
+
+func buildTopicFeeds(store *Articles) []*topicFeed {
+	var res []*topicFeed
+	for _, tag := range blogTags(store) {
+		// like the main feeds, only blog articles
+		articles := filterArticlesByTag(store.getBlogNotHidden(), tag, true)
+		f := &topicFeed{
+			URL:      topicFeedURL(tagURL(tag)),
+			Title:    siteConfig.Title + ": " + tag,
+			articles: latestArticles(articles),
+		}
+		res = append(res, f)
+	}
+	for _, s := range store.series {
+		f := &topicFeed{
+			URL:      topicFeedURL(s.URL()),
+			Title:    siteConfig.Title + ": " + s.Name,
+			articles: latestArticles(s.Articles),
+		}
+		res = append(res, f)
+	}
+	for _, c := range store.collections {
+		f := &topicFeed{
+			URL:      topicFeedURL(c.URL),
+			Title:    siteConfig.Title + ": " + c.Name,
+			articles: latestArticles(c.Articles),
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+func genTopicFeed(f *topicFeed) ([]byte, error) {
+	return genAtomXMLForArticles(f.articles, f.Title, f.URL)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestTopicFeeds(t *testing.T) {
+	store := makeFeedTestStore()
+	store.articles[0].Series = "Học Go"
+	store.series = buildSeries(store.articles)
+
+	feeds := buildTopicFeeds(store)
+	var urls []string
+	for _, f := range feeds {
+		urls = append(urls, f.URL)
+	}
+	exp := []string{"/tag/go/atom.xml", "/tag/note/atom.xml", "/tag/web/atom.xml", "/series/hoc-go/atom.xml"}
+	assert.Equal(t, exp, urls)
+	assert.Equal(t, "/book/go-cookbook/atom.xml", topicFeedURL("/book/go-cookbook.html"))
+
+	d, err := genTopicFeed(feeds[0])
+	assert.NoError(t, err)
+	s := string(d)
+	assert.True(t, strings.Contains(s, "https://ntheanh201.vercel.app/tag/go/atom.xml"))
+	assert.True(t, strings.Contains(s, "Xin chào"))
+	assert.False(t, strings.Contains(s, "A note"))
+
+	// tag feeds only have blog articles, like the main feeds
+	page := &Article{ID: "p1", Title: "Not in blog", Tags: []string{"go"}, PublishedOn: store.articles[0].PublishedOn}
+	store.articles = append(store.articles, page)
+	d, err = genTopicFeed(buildTopicFeeds(store)[0])
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(string(d), "Not in blog"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeArticlesArchiveForTag(store, "go", 1, &buf))
+	assert.True(t, strings.Contains(buf.String(), `href="/tag/go/atom.xml"`))
+}
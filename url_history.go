@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// url of an article changes when its title or url: override changes.
+// We remember all urls each Notion page ever had and redirect old urls
+// to the current one
+
+// file with url history, next to redirects.json
+const urlHistoryPath = "url_history.json"
+
+type urlHistory struct {
+	// maps Notion page id (not id: from metadata, which can change)
+	// to urls of the page, oldest first
+	Pages map[string][]string `json:"pages"`
+}
+
+func readURLHistory(path string) (*urlHistory, error) {
+	res := &urlHistory{
+		Pages: map[string][]string{},
+	}
+	d, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(d, res); err != nil {
+		return nil, err
+	}
+	if res.Pages == nil {
+		res.Pages = map[string][]string{}
+	}
+	return res, nil
+}
+
+func writeURLHistory(path string, h *urlHistory) error {
+	d, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(d, '\n'), 0644)
+}
+
+func (h *urlHistory) hasURL(pageID string, uri string) bool {
+	for _, s := range h.Pages[pageID] {
+		if s == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// historyID returns id under which urls of an article are remembered
+func historyID(a *Article) string {
+	return normalizeID(a.page.ID)
+}
+
+// update records current urls of articles. Returns true if anything changed
+func (h *urlHistory) update(articles []*Article) bool {
+	changed := false
+	for _, a := range articles {
+		id := historyID(a)
+		uri := a.URL()
+		if h.hasURL(id, uri) {
+			continue
+		}
+		h.Pages[id] = append(h.Pages[id], uri)
+		changed = true
+	}
+	return changed
+}
+
+// redirects returns 301 redirects from previous urls of articles to
+// their current urls. Urls that are now used by other articles are skipped.
+// If a url was used by more than one article, it's logged and redirected
+// to current url of the article whose url sorts first
+func (h *urlHistory) redirects(articles []*Article) []*Redirect {
+	currURLs := map[string]bool{}
+	for _, a := range articles {
+		currURLs[a.URL()] = true
+	}
+	var all []*Redirect
+	for _, a := range articles {
+		to := a.URL()
+		for _, from := range h.Pages[historyID(a)] {
+			if currURLs[from] {
+				continue
+			}
+			all = append(all, newRedirect(from, to, http.StatusMovedPermanently))
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].From != all[j].From {
+			return all[i].From < all[j].From
+		}
+		return all[i].To < all[j].To
+	})
+	var res []*Redirect
+	for _, r := range all {
+		if n := len(res); n > 0 && res[n-1].From == r.From {
+			if res[n-1].To != r.To {
+				logerrf(ctx(), "urlHistory: '%s' was used by more than one article, redirecting to '%s', not '%s'\n", r.From, res[n-1].To, r.To)
+			}
+			continue
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+// trackURLHistory updates url_history.json with current urls of articles
+// and adds redirects from their old urls
+func trackURLHistory(articles []*Article) {
+	h, err := readURLHistory(urlHistoryPath)
+	if err != nil {
+		// don't over-write history we failed to read
+		logerrf(ctx(), "trackURLHistory: readURLHistory('%s') failed with '%s'\n", urlHistoryPath, err)
+		return
+	}
+	if h.update(articles) {
+		must(writeURLHistory(urlHistoryPath, h))
+	}
+	for _, r := range h.redirects(articles) {
+		logvf("redirect: %s => %s\n", r.From, r.To)
+		addRedirect(r.From, r.To, r.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+)
+
+const (
+	pageID1 = "0d1b8f2e5c6a4b3f9e7d1c2b3a4f5e60"
+	pageID2 = "7a9c3e1f2b4d4c6e8f0a1b2c3d4e5f61"
+)
+
+func TestURLHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "url_history.json")
+	h, err := readURLHistory(path)
+	assert.NoError(t, err)
+	assert.Empty(t, h.Pages)
+
+	a1 := &Article{ID: "id1", page: &notionapi.Page{ID: pageID1}, urlOverride: "/article/first.html"}
+	a2 := &Article{ID: "id2", page: &notionapi.Page{ID: pageID2}, urlOverride: "/article/second.html"}
+	articles := []*Article{a1, a2}
+	assert.True(t, h.update(articles))
+	assert.False(t, h.update(articles))
+	assert.Empty(t, h.redirects(articles))
+
+	// a1 is renamed, a2 takes the old url of a1
+	a1.urlOverride = "/article/first-renamed.html"
+	a2.urlOverride = "/article/first.html"
+	assert.True(t, h.update(articles))
+	assert.NoError(t, writeURLHistory(path, h))
+
+	h, err = readURLHistory(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/article/first.html", "/article/first-renamed.html"}, h.Pages[pageID1])
+	redirects := h.redirects(articles)
+	assert.Len(t, redirects, 1)
+	assert.Equal(t, "/article/second.html", redirects[0].From)
+	assert.Equal(t, "/article/first.html", redirects[0].To)
+	assert.Equal(t, 301, redirects[0].Code)
+}
+
+func TestURLHistoryKeyedByPageID(t *testing.T) {
+	h := &urlHistory{Pages: map[string][]string{}}
+	a := &Article{ID: "old-id", page: &notionapi.Page{ID: pageID1}, urlOverride: "/article/old.html"}
+	assert.True(t, h.update([]*Article{a}))
+	a.urlOverride = "/article/new.html"
+	assert.True(t, h.update([]*Article{a}))
+	assert.Equal(t, []string{"/article/old.html", "/article/new.html"}, h.Pages[pageID1])
+
+	// id: in metadata changed, history stays with the page
+	a.ID = "renamed"
+	assert.False(t, h.update([]*Article{a}))
+	redirects := h.redirects([]*Article{a})
+	assert.Len(t, redirects, 1)
+	assert.Equal(t, "/article/old.html", redirects[0].From)
+}
+
+func TestURLHistoryConflictingRedirects(t *testing.T) {
+	h := &urlHistory{
+		Pages: map[string][]string{
+			pageID1: {"/article/shared.html", "/article/b.html"},
+			pageID2: {"/article/shared.html", "/article/a.html"},
+		},
+	}
+	a1 := &Article{ID: "page1", page: &notionapi.Page{ID: pageID1}, urlOverride: "/article/b.html"}
+	a2 := &Article{ID: "page2", page: &notionapi.Page{ID: pageID2}, urlOverride: "/article/a.html"}
+	for _, articles := range [][]*Article{{a1, a2}, {a2, a1}} {
+		redirects := h.redirects(articles)
+		assert.Len(t, redirects, 1)
+		assert.Equal(t, "/article/shared.html", redirects[0].From)
+		assert.Equal(t, "/article/a.html", redirects[0].To)
+	}
+}